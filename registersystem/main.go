@@ -15,14 +15,21 @@ TODO:
 */
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
+	"regexp"
 	"registersystem/webapi"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 var (
@@ -33,9 +40,63 @@ var (
 	group        string
 	hostname     string
 	vaultAddress string
+	kvprefix     string
+	adminmount   string
+	adminpath    string
 	task         string
+	wait         time.Duration
+	cleanup      string
+	jsonOutput   bool
+	earliest     string
+	security     bool
+	stagger      time.Duration
+	testmode     bool
+	scriptfile   string
+	runas        string
+	rungroup     string
+	scripttime   int
+	actionID     int
+	cancel       bool
+	packages     string
+	check        bool
+	meta         metaFlag
 )
 
+// metaFlag collects the custom info values of the repeatable -meta key=value flag.
+type metaFlag map[string]string
+
+var metaKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (m *metaFlag) String() string {
+	var pairs []string
+	for k, v := range *m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m *metaFlag) Set(line string) error {
+	key, value, found := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !found || !metaKey.MatchString(key) {
+		return fmt.Errorf("expected key=value with a key of letters, digits, - and _, got %q", line)
+	}
+	if key == webapi.ExpiresAtKey {
+		if _, err := webapi.ParseCustomTime(value); err != nil {
+			return err
+		}
+	}
+	if *m == nil {
+		*m = make(metaFlag)
+	}
+	if _, exists := (*m)[key]; exists {
+		return fmt.Errorf("key %s given twice", key)
+	}
+	(*m)[key] = value
+	return nil
+}
+
 // func init() {
 // 	flag.BoolVar(&verbose, "v", false, "verbose output")
 // 	flag.StringVar(&roleID, "r", "", "roleID")
@@ -52,15 +113,45 @@ func registerFlags(fs *flag.FlagSet) {
 	fs.StringVar(&roleID, "r", "", "Role ID")
 	fs.StringVar(&secretID, "s", "", "Secret ID")
 	fs.StringVar(&group, "g", "", "SUSE Manager Group")
-	fs.StringVar(&hostname, "h", "", "Hostname")
+	fs.StringVar(&hostname, "h", "", "Hostname, a comma separated list of hostnames for group tasks (default all systems of the group)")
 	fs.StringVar(&vaultAddress, "a", "", "Vault Address")
-	fs.StringVar(&task, "t", "", "Task [add | delete]")
+	fs.StringVar(&kvprefix, "kvprefix", webapi.DefaultKVPrefix, "Prefix of the mounts of the KV stores of the groups")
+	fs.StringVar(&adminmount, "adminmount", webapi.DefaultAdminMount, "Mount of the KV store with the SUSE Manager credentials")
+	fs.StringVar(&adminpath, "adminpath", webapi.DefaultAdminPath, "Path of the SUSE Manager credentials in the admin KV store")
+	fs.Var(&meta, "meta", "Custom info value key=value of the added system, f.i. owner=alice, ticket=INC-1234 or expires_at=2026-12-31, repeatable (registered_at is set automatically)")
+	fs.StringVar(&task, "t", "", "Task [add | delete | remove | accept | status | list | patch | reboot | highstate | script | action | install | uninstall | channels]")
+	fs.StringVar(&cleanup, "cleanup", webapi.CleanupFailOnError, "Cleanup type of delete [FAIL_ON_CLEANUP_ERR | NO_CLEANUP | FORCE_DELETE], FORCE_DELETE forces the delete, if SUSE Manager refuses the delete with cleanup")
+	fs.DurationVar(&wait, "wait", 0, "Time to wait for the registration of an accepted system (default 5m), for the results of a script or for an action")
+	fs.StringVar(&earliest, "earliest", "", "Earliest time of scheduled actions in RFC3339 format, f.i. 2026-10-18T22:00:00+02:00 (default now)")
+	fs.BoolVar(&security, "security", false, "Apply security patches only")
+	fs.DurationVar(&stagger, "stagger", 0, "Delay between the reboots of two systems, f.i. 10m")
+	fs.BoolVar(&testmode, "test", false, "Apply highstate in test mode (test=true)")
+	fs.StringVar(&scriptfile, "script", "", "Script file to run on the systems")
+	fs.StringVar(&runas, "runas", "root", "User to run the script")
+	fs.StringVar(&rungroup, "rungroup", "root", "Group to run the script")
+	fs.IntVar(&scripttime, "scripttimeout", 600, "Timeout of the script in seconds")
+	fs.IntVar(&actionID, "id", 0, "ID of the action to inspect or cancel")
+	fs.BoolVar(&cancel, "cancel", false, "Cancel the action")
+	fs.StringVar(&packages, "packages", "", "Comma separated list of packages to install or uninstall")
+	fs.BoolVar(&check, "check", false, "With task add, only report the systems of the group whose channels deviate from the configuration")
+	fs.BoolVar(&jsonOutput, "json", false, "Output in JSON format")
 	fs.BoolVar(&verbose, "v", false, "Verbose output")
 }
 
 func customUsage() {
-	fmt.Fprintf(os.Stderr, "Usage of %s: -r [roleID] -s [secretID] -a [URL Vault] -h [hostname] -g [Group] -t [add|delete] -v [verbose]\n\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "The program add a system to a SUSE Manager Systemgroup or delete a system from the SUSE Manager.\n\nParameter:\n")
+	fmt.Fprintf(os.Stderr, "Usage of %s: -r [roleID] -s [secretID] -a [URL Vault] -h [hostname] -g [Group] -t [add|delete|remove|accept|status|list|patch|reboot|highstate|script|action|install|uninstall|channels] -v [verbose]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "The program add a system to a SUSE Manager Systemgroup or delete a system from the SUSE Manager.\n")
+	fmt.Fprintf(os.Stderr, "The task remove removes a system from the Systemgroup without deleting it from the SUSE Manager.\n")
+	fmt.Fprintf(os.Stderr, "The task accept accepts the pending salt key of the system and add it to the Systemgroup after registration.\n")
+	fmt.Fprintf(os.Stderr, "The task status shows the state of the system in the SUSE Manager.\n")
+	fmt.Fprintf(os.Stderr, "The task list shows the last checkin and the custom info values of the systems or all systems of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task patch schedules the relevant patches for the systems or all systems of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task reboot schedules a reboot of the systems or all systems of the Systemgroup, which require a reboot.\n")
+	fmt.Fprintf(os.Stderr, "The task highstate schedules a Salt highstate for the systems or all systems of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task script runs a script on the systems or all systems of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task action shows, waits for or cancels an action of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The tasks install and uninstall schedule the installation or removal of packages on the systems or all systems of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task channels (or add -check) reports the systems, which are not subscribed to the software channels of the Systemgroup.\n\nParameter:\n")
 
 	flag.PrintDefaults()
 }
@@ -90,14 +181,258 @@ func getTask(line string) string {
 		return "add"
 	case "delete", "d":
 		return "delete"
+	case "remove":
+		return "remove"
+	case "accept":
+		return "accept"
+	case "status", "s":
+		return "status"
+	case "list", "l":
+		return "list"
+	case "patch", "p":
+		return "patch"
+	case "reboot", "r":
+		return "reboot"
+	case "highstate", "hs":
+		return "highstate"
+	case "script":
+		return "script"
+	case "action":
+		return "action"
+	case "install", "i":
+		return "install"
+	case "uninstall", "u":
+		return "uninstall"
+	case "channels":
+		return "channels"
+	default:
+		return "error"
+	}
+}
+
+// isGroupTask reports, if the task acts on a list of systems or on all systems of the group.
+func isGroupTask(line string) bool {
+	switch line {
+	case "list", "patch", "reboot", "highstate", "script", "action", "install", "uninstall", "channels":
+		return true
+	default:
+		return false
+	}
+}
+
+func splitList(line string) []string {
+	var hostnames []string
+	for _, h := range strings.Split(line, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" {
+			hostnames = append(hostnames, h)
+		}
+	}
+	return hostnames
+}
+
+// configValue returns a value of the group configuration or an empty string.
+func configValue(config map[string]interface{}, key string) string {
+	if config[key] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s", config[key])
+}
+
+// metaValues returns the custom info values of an added system including the time of the registration.
+func metaValues(m metaFlag, now time.Time) map[string]string {
+	values := make(map[string]string)
+	for k, v := range m {
+		values[k] = v
+	}
+	if _, exists := values[webapi.RegisteredAtKey]; !exists {
+		values[webapi.RegisteredAtKey] = now.UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+// formatValues formats custom info values as sorted key=value pairs.
+func formatValues(values map[string]string) string {
+	var pairs []string
+	for k, v := range values {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func parseEarliest(line string) (time.Time, error) {
+	if isEmpty(line) {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, line)
+}
+
+func getCleanupType(line string) string {
+	switch strings.ToUpper(line) {
+	case webapi.CleanupFailOnError:
+		return webapi.CleanupFailOnError
+	case webapi.CleanupNone:
+		return webapi.CleanupNone
+	case webapi.CleanupForce:
+		return webapi.CleanupForce
 	default:
 		return "error"
 	}
 }
 
+// setupSystem applies the software and configuration channels and the custom values of the group to a system, which
+// was added to the group.
+func setupSystem(w io.Writer, sessioncookie, sumaurl, hostname, network, basechannel string, childchannels, configchannels []string, earliestTime time.Time) error {
+
+	if basechannel != "" || len(childchannels) > 0 {
+		action, err := webapi.SumaEnforceChannels(sessioncookie, sumaurl, hostname, network, basechannel, childchannels, earliestTime, verbose)
+		if err != nil {
+			return fmt.Errorf("could not change the channels of %s. %v", hostname, err)
+		}
+		if action.ActionID != 0 {
+			fmt.Fprintf(w, "Scheduled action %d to change the channels of %s\n", action.ActionID, hostname)
+		}
+	}
+
+	if len(configchannels) > 0 {
+		err := webapi.SumaSubscribeConfigChannels(sessioncookie, sumaurl, hostname, network, configchannels, verbose)
+		if err != nil {
+			return fmt.Errorf("could not subscribe %s to the configuration channels. %v", hostname, err)
+		}
+		fmt.Fprintf(w, "Subscribed %s to the configuration channels %s\n", hostname, strings.Join(configchannels, ", "))
+	}
+
+	err := webapi.SumaSetCustomValues(sessioncookie, sumaurl, hostname, network, metaValues(meta, time.Now()), verbose)
+	if err != nil {
+		return fmt.Errorf("could not set custom values of %s. %v", hostname, err)
+	}
+
+	return nil
+}
+
+// writeJSON prints v as indented JSON, a nil list as empty list.
+func writeJSON(w io.Writer, v any) error {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = []struct{}{}
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func writeStatus(w io.Writer, status webapi.SystemStatusType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, status)
+	}
+
+	fmt.Fprintf(w, "System:           %s\n", status.Name)
+	fmt.Fprintf(w, "Server ID:        %d\n", status.ID)
+	fmt.Fprintf(w, "IP:               %s\n", status.IP)
+	fmt.Fprintf(w, "Groups:           %s\n", strings.Join(status.Groups, ", "))
+	fmt.Fprintf(w, "Base channel:     %s\n", status.BaseChannel)
+	fmt.Fprintf(w, "Child channels:   %s\n", strings.Join(status.ChildChannels, ", "))
+	fmt.Fprintf(w, "Last checkin:     %s\n", status.LastCheckin)
+	fmt.Fprintf(w, "Pending actions:  %d\n", status.PendingActions)
+	fmt.Fprintf(w, "Relevant patches: %d\n", status.RelevantPatches)
+	fmt.Fprintf(w, "Reboot required:  %t\n", status.RebootRequired)
+	_, err := fmt.Fprintf(w, "Custom values:    %s\n", formatValues(status.CustomValues))
+	return err
+}
+
+func writeSystemInfo(w io.Writer, infos []webapi.SystemInfoType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, infos)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SYSTEM\tIP\tLAST CHECKIN\tCUSTOM VALUES")
+	for _, i := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", i.Name, i.IP, i.LastCheckin, formatValues(i.CustomValues))
+	}
+	return tw.Flush()
+}
+
+func writeActions(w io.Writer, actions []webapi.ActionType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, actions)
+	}
+
+	for _, a := range actions {
+		if _, err := fmt.Fprintf(w, "Scheduled action %d for %s\n", a.ActionID, a.System); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeScriptResults(w io.Writer, results []webapi.ScriptResultType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, results)
+	}
+
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "=== %s (exit code %d) ===\n%s\n", r.System, r.ReturnCode, r.Output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeActionStatus(w io.Writer, status webapi.ActionStatusType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, status)
+	}
+
+	names := func(systems []webapi.SystemType) string {
+		var n []string
+		for _, s := range systems {
+			n = append(n, s.Name)
+		}
+		return strings.Join(n, ", ")
+	}
+
+	fmt.Fprintf(w, "Action:      %d\n", status.ActionID)
+	fmt.Fprintf(w, "In progress: %s\n", names(status.InProgress))
+	fmt.Fprintf(w, "Completed:   %s\n", names(status.Completed))
+	_, err := fmt.Fprintf(w, "Failed:      %s\n", names(status.Failed))
+	return err
+}
+
+func writeDeviations(w io.Writer, deviations []webapi.ChannelDeviationType, asJSON bool) error {
+
+	if asJSON {
+		return writeJSON(w, deviations)
+	}
+
+	for _, d := range deviations {
+		_, err := fmt.Fprintf(w, "%s: base channel %s, child channels [%s], expected base channel %s, child channels [%s]\n",
+			d.System, d.BaseChannel, strings.Join(d.ChildChannels, ", "), d.WantBase, strings.Join(d.WantChildren, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func checkFlag(proleID, psecretID, pgroup, phostname, pvault, ptask string) bool {
 
-	if !isFQDN(phostname) || isEmpty(phostname) {
+	if isGroupTask(getTask(ptask)) {
+		for _, h := range splitList(phostname) {
+			if !isFQDN(h) {
+				log.Printf("Please enter the FQDN Hostname.")
+				return false
+			}
+		}
+	} else if !isFQDN(phostname) || isEmpty(phostname) {
 		log.Printf("Please enter the FQDN Hostname.")
 		return false
 	}
@@ -148,7 +483,26 @@ func main() {
 		fmt.Println("DEBUG MAIN Parameter: group:", group)
 		fmt.Println("DEBUG MAIN Parameter: hostname:", hostname)
 		fmt.Println("DEBUG MAIN Parameter: vaultAddress:", vaultAddress)
+		fmt.Println("DEBUG MAIN Parameter: kvprefix:", kvprefix)
+		fmt.Println("DEBUG MAIN Parameter: adminmount:", adminmount)
+		fmt.Println("DEBUG MAIN Parameter: adminpath:", adminpath)
 		fmt.Println("DEBUG MAIN Parameter: task:", task)
+		fmt.Println("DEBUG MAIN Parameter: wait:", wait)
+		fmt.Println("DEBUG MAIN Parameter: cleanup:", cleanup)
+		fmt.Println("DEBUG MAIN Parameter: json:", jsonOutput)
+		fmt.Println("DEBUG MAIN Parameter: earliest:", earliest)
+		fmt.Println("DEBUG MAIN Parameter: security:", security)
+		fmt.Println("DEBUG MAIN Parameter: stagger:", stagger)
+		fmt.Println("DEBUG MAIN Parameter: test:", testmode)
+		fmt.Println("DEBUG MAIN Parameter: script:", scriptfile)
+		fmt.Println("DEBUG MAIN Parameter: runas:", runas)
+		fmt.Println("DEBUG MAIN Parameter: rungroup:", rungroup)
+		fmt.Println("DEBUG MAIN Parameter: scripttimeout:", scripttime)
+		fmt.Println("DEBUG MAIN Parameter: id:", actionID)
+		fmt.Println("DEBUG MAIN Parameter: cancel:", cancel)
+		fmt.Println("DEBUG MAIN Parameter: packages:", packages)
+		fmt.Println("DEBUG MAIN Parameter: check:", check)
+		fmt.Println("DEBUG MAIN Parameter: meta:", meta.String())
 	}
 
 	// no args
@@ -157,13 +511,32 @@ func main() {
 		os.Exit(1)
 	}
 
+	// add in check mode only reports the channel deviations of the group
+	if check && getTask(task) == "add" {
+		task = "channels"
+	}
+
 	if !checkFlag(roleID, secretID, group, hostname, vaultAddress, task) {
 		os.Exit(1)
 	}
 
+	if isEmpty(kvprefix) || isEmpty(adminmount) || isEmpty(adminpath) {
+		log.Fatalf("please enter the kvprefix, the adminmount and the adminpath.")
+	}
+
 	task = getTask(task)
 	if task == "error" {
-		log.Fatalf("please enter a valid task [add | delete].")
+		log.Fatalf("please enter a valid task [add | delete | remove | accept | status | list | patch | reboot | highstate | script | action | install | uninstall | channels].")
+	}
+
+	cleanup = getCleanupType(cleanup)
+	if cleanup == "error" {
+		log.Fatalf("please enter a valid cleanup type [FAIL_ON_CLEANUP_ERR | NO_CLEANUP | FORCE_DELETE].")
+	}
+
+	earliestTime, err := parseEarliest(earliest)
+	if err != nil {
+		log.Fatalf("please enter the earliest time in RFC3339 format. %v", err)
 	}
 
 	client, err := webapi.VaultLogin(roleID, secretID, vaultAddress, verbose)
@@ -173,7 +546,7 @@ func main() {
 
 	defer webapi.VaultLogout(client, verbose)
 
-	suma, err := webapi.VaultGetSecrets(client, vaultAddress, "dagobah", "suma", verbose)
+	suma, err := webapi.VaultGetSecrets(client, vaultAddress, adminmount, adminpath, verbose)
 	if err != nil {
 		log.Fatalf("error getting vault secrets: %v", err)
 	}
@@ -194,7 +567,7 @@ func main() {
 	sumapassword := fmt.Sprintf("%s", suma["password"])
 	sumaurl := fmt.Sprintf("%s", suma["url"])
 
-	secretData, err := webapi.VaultGetSecrets(client, vaultAddress, group, "config", verbose)
+	secretData, err := webapi.VaultGetSecrets(client, vaultAddress, kvprefix+group, "config", verbose)
 	if err != nil {
 		log.Fatalf("error retrieving secret: %v", err)
 	}
@@ -205,8 +578,16 @@ func main() {
 
 	network := fmt.Sprintf("%s", secretData["network"])
 
+	// software and configuration channels of the group are optional
+	basechannel := configValue(secretData, "base_channel")
+	childchannels := splitList(configValue(secretData, "child_channels"))
+	configchannels := splitList(configValue(secretData, "config_channels"))
+
 	if verbose {
 		log.Printf("DEBUG MAIN: network = %s\n", network)
+		log.Printf("DEBUG MAIN: base_channel = %s\n", basechannel)
+		log.Printf("DEBUG MAIN: child_channels = %v\n", childchannels)
+		log.Printf("DEBUG MAIN: config_channels = %v\n", configchannels)
 	}
 
 	sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
@@ -232,11 +613,23 @@ func main() {
 				fmt.Printf("Got result: %d\n", result)
 			}
 		}
+		if err := setupSystem(os.Stdout, sessioncookie, sumaurl, hostname, network, basechannel, childchannels, configchannels, earliestTime); err != nil {
+			log.Fatal(err)
+		}
 	case "delete":
-		result, err := webapi.SumaDeleteSystem(sessioncookie, sumaurl, hostname, network, verbose)
+		if len(configchannels) > 0 {
+			err := webapi.SumaUnsubscribeConfigChannels(sessioncookie, sumaurl, hostname, network, configchannels, verbose)
+			if err != nil {
+				log.Printf("could not unsubscribe %s from the configuration channels. %v", hostname, err)
+			}
+		}
+		result, forced, err := webapi.SumaDeleteSystem(sessioncookie, sumaurl, hostname, network, cleanup, verbose)
 		if err != nil {
 			log.Fatalf("Could not delete System from Suma, errorcode: %v", err)
 		}
+		if forced {
+			log.Printf("cleanup of %s failed, forced delete with %s\n", hostname, webapi.CleanupForce)
+		}
 		if result != http.StatusOK {
 			log.Fatalf("an error occured, got http error %d", result)
 		} else {
@@ -245,6 +638,182 @@ func main() {
 				log.Printf("got result: %d\n", result)
 			}
 		}
+	case "remove":
+		if len(configchannels) > 0 {
+			err := webapi.SumaUnsubscribeConfigChannels(sessioncookie, sumaurl, hostname, network, configchannels, verbose)
+			if err != nil {
+				log.Fatalf("could not unsubscribe %s from the configuration channels. %v", hostname, err)
+			}
+		}
+		result, err := webapi.SumaRemoveSystem(sessioncookie, sumaurl, hostname, group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not remove system from group %s. %v", group, err)
+		}
+		if result != http.StatusOK {
+			log.Fatalf("an error occured, got http error %d", result)
+		}
+		fmt.Printf("Removed system %s successfully from group %s\n", hostname, group)
+	case "accept":
+		result, err := webapi.SumaAcceptSaltKey(sessioncookie, sumaurl, hostname, group, network, wait, verbose)
+		if err != nil {
+			log.Fatalf("could not accept salt key of %s. %v", hostname, err)
+		}
+		if result != http.StatusOK {
+			fmt.Fprintf(os.Stderr, "an error occured, got http error %d", result)
+			os.Exit(1)
+		} else {
+			fmt.Printf("Accepted salt key of %s and add system successfully to group %s\n", hostname, group)
+		}
+		if err := setupSystem(os.Stdout, sessioncookie, sumaurl, hostname, network, basechannel, childchannels, configchannels, earliestTime); err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		status, err := webapi.SumaGetSystemStatus(sessioncookie, sumaurl, hostname, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get status of %s. %v", hostname, err)
+		}
+		if err := writeStatus(os.Stdout, status, jsonOutput); err != nil {
+			log.Fatalf("could not write status of %s. %v", hostname, err)
+		}
+	case "list":
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		infos, err := webapi.SumaListSystemInfo(sessioncookie, sumaurl, systems, verbose)
+		if err != nil {
+			log.Fatalf("could not get custom values. %v", err)
+		}
+		if err := writeSystemInfo(os.Stdout, infos, jsonOutput); err != nil {
+			log.Fatalf("could not write systems. %v", err)
+		}
+	case "patch":
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		advisoryType := ""
+		if security {
+			advisoryType = webapi.AdvisorySecurity
+		}
+		actions, err := webapi.SumaScheduleApplyErrata(sessioncookie, sumaurl, systems, advisoryType, earliestTime, verbose)
+		if werr := writeActions(os.Stdout, actions, jsonOutput); werr != nil {
+			log.Printf("could not write actions. %v", werr)
+		}
+		if err != nil {
+			log.Fatalf("could not schedule patches. %v", err)
+		}
+	case "reboot":
+		hostnames := splitList(hostname)
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, hostnames, group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		// without hostnames only the systems requiring a reboot are rebooted
+		if len(hostnames) == 0 {
+			systems, err = webapi.SumaFilterRebootRequired(sessioncookie, sumaurl, systems, verbose)
+			if err != nil {
+				log.Fatalf("could not get systems requiring a reboot. %v", err)
+			}
+		}
+		actions, err := webapi.SumaScheduleReboot(sessioncookie, sumaurl, systems, earliestTime, stagger, verbose)
+		if werr := writeActions(os.Stdout, actions, jsonOutput); werr != nil {
+			log.Printf("could not write actions. %v", werr)
+		}
+		if err != nil {
+			log.Fatalf("could not schedule reboot. %v", err)
+		}
+	case "highstate":
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		actions, err := webapi.SumaScheduleApplyHighstate(sessioncookie, sumaurl, systems, earliestTime, testmode, verbose)
+		if err != nil {
+			log.Fatalf("could not schedule highstate. %v", err)
+		}
+		if err := writeActions(os.Stdout, actions, jsonOutput); err != nil {
+			log.Fatalf("could not write actions. %v", err)
+		}
+	case "script":
+		script, err := os.ReadFile(scriptfile)
+		if err != nil {
+			log.Fatalf("could not read script %s. %v", scriptfile, err)
+		}
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		actions, err := webapi.SumaScheduleScriptRun(sessioncookie, sumaurl, systems, string(script), runas, rungroup, scripttime, earliestTime, verbose)
+		if err != nil {
+			log.Fatalf("could not schedule script. %v", err)
+		}
+		if wait <= 0 || len(actions) == 0 {
+			if err := writeActions(os.Stdout, actions, jsonOutput); err != nil {
+				log.Fatalf("could not write actions. %v", err)
+			}
+			break
+		}
+		results, err := webapi.SumaWaitForScriptResults(sessioncookie, sumaurl, actions[0].ActionID, systems, wait, verbose)
+		if werr := writeScriptResults(os.Stdout, results, jsonOutput); werr != nil {
+			log.Printf("could not write script results. %v", werr)
+		}
+		if err != nil {
+			log.Fatalf("could not get all script results. %v", err)
+		}
+	case "action":
+		if actionID <= 0 {
+			log.Fatalf("please enter the ID of the action.")
+		}
+		status, err := webapi.SumaGetActionStatus(sessioncookie, sumaurl, actionID, verbose)
+		if err != nil {
+			log.Fatalf("could not get action %d. %v", actionID, err)
+		}
+		err = webapi.SumaCheckActionSystems(sessioncookie, sumaurl, status, group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not access action %d. %v", actionID, err)
+		}
+		if cancel {
+			err = webapi.SumaCancelAction(sessioncookie, sumaurl, actionID, verbose)
+			if err != nil {
+				log.Fatalf("could not cancel action %d. %v", actionID, err)
+			}
+			fmt.Printf("Action %d successfully cancelled\n", actionID)
+			break
+		}
+		if wait > 0 {
+			status, err = webapi.SumaWaitForAction(sessioncookie, sumaurl, actionID, wait, verbose)
+		}
+		if werr := writeActionStatus(os.Stdout, status, jsonOutput); werr != nil {
+			log.Printf("could not write action %d. %v", actionID, werr)
+		}
+		if err != nil {
+			log.Fatalf("could not wait for action %d. %v", actionID, err)
+		}
+	case "install", "uninstall":
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		actions, err := webapi.SumaSchedulePackages(sessioncookie, sumaurl, systems, splitList(packages), task == "uninstall", earliestTime, verbose)
+		if werr := writeActions(os.Stdout, actions, jsonOutput); werr != nil {
+			log.Printf("could not write actions. %v", werr)
+		}
+		if err != nil {
+			log.Fatalf("could not schedule packages. %v", err)
+		}
+	case "channels":
+		systems, err := webapi.SumaGetTargetSystems(sessioncookie, sumaurl, splitList(hostname), group, network, verbose)
+		if err != nil {
+			log.Fatalf("could not get systems of group %s. %v", group, err)
+		}
+		deviations, err := webapi.SumaGetChannelDeviations(sessioncookie, sumaurl, systems, basechannel, childchannels, verbose)
+		if werr := writeDeviations(os.Stdout, deviations, jsonOutput); werr != nil {
+			log.Printf("could not write channel deviations. %v", werr)
+		}
+		if err != nil {
+			log.Fatalf("could not check channels. %v", err)
+		}
 
 	}
 	os.Exit(0)
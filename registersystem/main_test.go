@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"os"
+	"registersystem/webapi"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Test isFQDN
@@ -78,6 +83,25 @@ func TestGetTask(t *testing.T) {
 		{"a", "add"},
 		{"delete", "delete"},
 		{"d", "delete"},
+		{"remove", "remove"},
+		{"accept", "accept"},
+		{"status", "status"},
+		{"s", "status"},
+		{"list", "list"},
+		{"l", "list"},
+		{"patch", "patch"},
+		{"p", "patch"},
+		{"reboot", "reboot"},
+		{"r", "reboot"},
+		{"highstate", "highstate"},
+		{"hs", "highstate"},
+		{"script", "script"},
+		{"action", "action"},
+		{"install", "install"},
+		{"i", "install"},
+		{"uninstall", "uninstall"},
+		{"u", "uninstall"},
+		{"channels", "channels"},
 		{"firefox", "error"},
 	}
 
@@ -89,6 +113,101 @@ func TestGetTask(t *testing.T) {
 	}
 }
 
+// Test getCleanupType
+func TestGetCleanupType(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"FAIL_ON_CLEANUP_ERR", "FAIL_ON_CLEANUP_ERR"},
+		{"no_cleanup", "NO_CLEANUP"},
+		{"Force_Delete", "FORCE_DELETE"},
+		{"", "error"},
+		{"firefox", "error"},
+	}
+
+	for _, tt := range tests {
+		got := getCleanupType(tt.line)
+		if got != tt.want {
+			t.Errorf("getCleanupType(%q) = %v; want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// Test writeStatus
+func TestWriteStatus(t *testing.T) {
+	status := webapi.SystemStatusType{
+		ID:             42,
+		Name:           "host.example.com",
+		Groups:         []string{"group"},
+		BaseChannel:    "sles15-sp6-pool",
+		RebootRequired: true,
+		CustomValues:   map[string]string{"owner": "alice"},
+	}
+
+	var text bytes.Buffer
+	if err := writeStatus(&text, status, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(text.String(), "Server ID:        42") || !strings.Contains(text.String(), "Reboot required:  true") ||
+		!strings.Contains(text.String(), "Custom values:    owner=alice") {
+		t.Errorf("unexpected text output: %s", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeStatus(&out, status, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got webapi.SystemStatusType
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if got.ID != 42 || got.BaseChannel != "sles15-sp6-pool" || !got.RebootRequired {
+		t.Errorf("unexpected JSON output: %+v", got)
+	}
+}
+
+// Test writeScriptResults
+func TestWriteScriptResults(t *testing.T) {
+	results := []webapi.ScriptResultType{{System: "a.example.com", ReturnCode: 1, Output: "down"}}
+
+	var text bytes.Buffer
+	if err := writeScriptResults(&text, results, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text.String() != "=== a.example.com (exit code 1) ===\ndown\n" {
+		t.Errorf("unexpected text output: %q", text.String())
+	}
+}
+
+// Test writeActionStatus
+func TestWriteActionStatus(t *testing.T) {
+	status := webapi.ActionStatusType{
+		ActionID:  500,
+		Completed: []webapi.SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}},
+	}
+
+	var text bytes.Buffer
+	if err := writeActionStatus(&text, status, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(text.String(), "Completed:   a.example.com, b.example.com") {
+		t.Errorf("unexpected text output: %s", text.String())
+	}
+}
+
+// Test configValue
+func TestConfigValue(t *testing.T) {
+	config := map[string]interface{}{"base_channel": "sles15-sp6-pool"}
+
+	if got := configValue(config, "base_channel"); got != "sles15-sp6-pool" {
+		t.Errorf("configValue(base_channel) = %q; want sles15-sp6-pool", got)
+	}
+	if got := configValue(config, "child_channels"); got != "" {
+		t.Errorf("configValue(child_channels) = %q; want empty", got)
+	}
+}
+
 // Test checkFlag
 func TestCheckFlag(t *testing.T) {
 	valid := checkFlag("role", "secret", "group", "host.example.com", "http://vault", "add")
@@ -113,6 +232,75 @@ func TestCheckFlag(t *testing.T) {
 			t.Errorf("Expected checkFlag to fail for invalid input set #%d: %+v", i, inv)
 		}
 	}
+
+	// group tasks act on all systems of the group without hostname
+	if !checkFlag("role", "secret", "group", "", "http://vault", "patch") {
+		t.Error("Expected group task without hostname to pass checkFlag")
+	}
+	if !checkFlag("role", "secret", "group", "a.example.com, b.example.com", "http://vault", "patch") {
+		t.Error("Expected group task with hostname list to pass checkFlag")
+	}
+	if checkFlag("role", "secret", "group", "a.example.com,notfqdn", "http://vault", "patch") {
+		t.Error("Expected group task with invalid hostname to fail checkFlag")
+	}
+}
+
+// Test splitList
+func TestSplitHostnames(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"a.example.com", []string{"a.example.com"}},
+		{"a.example.com, b.example.com,", []string{"a.example.com", "b.example.com"}},
+	}
+
+	for _, tt := range tests {
+		got := splitList(tt.line)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitList(%q) = %v; want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// Test parseEarliest
+func TestParseEarliest(t *testing.T) {
+	got, err := parseEarliest("")
+	if err != nil || !got.IsZero() {
+		t.Errorf("parseEarliest(\"\") = %v, %v; want zero time", got, err)
+	}
+
+	got, err = parseEarliest("2026-10-18T22:00:00+02:00")
+	if err != nil || got.UTC().Hour() != 20 {
+		t.Errorf("parseEarliest() = %v, %v; want 20:00 UTC", got, err)
+	}
+
+	if _, err = parseEarliest("tomorrow"); err == nil {
+		t.Error("Expected parseEarliest to fail for invalid time")
+	}
+}
+
+// Test writeActions
+func TestWriteActions(t *testing.T) {
+	actions := []webapi.ActionType{{System: "a.example.com", ActionID: 100}}
+
+	var text bytes.Buffer
+	if err := writeActions(&text, actions, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text.String() != "Scheduled action 100 for a.example.com\n" {
+		t.Errorf("unexpected text output: %q", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeActions(&out, actions, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []webapi.ActionType
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil || len(got) != 1 || got[0].ActionID != 100 {
+		t.Errorf("unexpected JSON output: %s", out.String())
+	}
 }
 
 // Test flag parsing and global variable assignment
@@ -129,7 +317,10 @@ func TestFlagParsing(t *testing.T) {
 	origVaultAddress := vaultAddress
 	origTask := task
 	origVerbose := verbose
+	origMeta := meta
+	origKVPrefix, origAdminMount, origAdminPath := kvprefix, adminmount, adminpath
 	defer func() {
+		kvprefix, adminmount, adminpath = origKVPrefix, origAdminMount, origAdminPath
 		roleID = origRoleID
 		secretID = origSecretID
 		group = origGroup
@@ -137,7 +328,9 @@ func TestFlagParsing(t *testing.T) {
 		vaultAddress = origVaultAddress
 		task = origTask
 		verbose = origVerbose
+		meta = origMeta
 	}()
+	meta = nil
 
 	os.Args = []string{
 		"cmd",
@@ -147,6 +340,9 @@ func TestFlagParsing(t *testing.T) {
 		"-h", "host.example.com",
 		"-a", "http://vault",
 		"-t", "add",
+		"-meta", "owner=alice",
+		"-meta", "ticket=INC-1234",
+		"-kvprefix", "kv-lab2-",
 		"-v",
 	}
 
@@ -177,4 +373,95 @@ func TestFlagParsing(t *testing.T) {
 	if task != "add" {
 		t.Errorf("Expected task to be 'add', got %q", task)
 	}
+	if meta["owner"] != "alice" || meta["ticket"] != "INC-1234" {
+		t.Errorf("Expected meta owner and ticket, got %v", meta)
+	}
+	if kvprefix != "kv-lab2-" || adminmount != "kv-clab-dagobah" || adminpath != "suma" {
+		t.Errorf("Expected kvprefix kv-lab2- and the default admin location, got %q %q %q", kvprefix, adminmount, adminpath)
+	}
+}
+
+// Test the -meta flag
+func TestMetaFlag(t *testing.T) {
+	var m metaFlag
+
+	for _, line := range []string{"owner=alice", "ticket=INC-1234", "expires_at=2026-12-31", "note=a=b"} {
+		if err := m.Set(line); err != nil {
+			t.Errorf("unexpected error for %q: %v", line, err)
+		}
+	}
+	if m["note"] != "a=b" {
+		t.Errorf("expected value to keep '=', got %q", m["note"])
+	}
+	if m.String() != "expires_at=2026-12-31,note=a=b,owner=alice,ticket=INC-1234" {
+		t.Errorf("unexpected String(): %s", m.String())
+	}
+
+	for _, line := range []string{"owner", "=alice", "own er=alice", "owner=bob", "expires_at=tomorrow"} {
+		if err := m.Set(line); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
+
+// Test metaValues
+func TestMetaValues(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	values := metaValues(metaFlag{"owner": "alice"}, now)
+	if values["owner"] != "alice" || values[webapi.RegisteredAtKey] != "2026-10-18T12:00:00Z" {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	values = metaValues(metaFlag{webapi.RegisteredAtKey: "2026-01-01"}, now)
+	if values[webapi.RegisteredAtKey] != "2026-01-01" {
+		t.Errorf("expected given registered_at to be kept, got %v", values)
+	}
+}
+
+// Test writeSystemInfo
+func TestWriteSystemInfo(t *testing.T) {
+	infos := []webapi.SystemInfoType{{
+		ID:           1,
+		Name:         "a.example.com",
+		IP:           "192.168.1.10",
+		LastCheckin:  "2026-10-01T10:00:00Z",
+		CustomValues: map[string]string{"ticket": "INC-1234", "owner": "alice"},
+	}}
+
+	var text bytes.Buffer
+	if err := writeSystemInfo(&text, infos, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(text.String(), "a.example.com") || !strings.Contains(text.String(), "owner=alice, ticket=INC-1234") {
+		t.Errorf("unexpected text output: %s", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeSystemInfo(&out, infos, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []webapi.SystemInfoType
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(got) != 1 || got[0].CustomValues["owner"] != "alice" {
+		t.Errorf("unexpected JSON output: %+v", got)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	var actions []webapi.ActionType
+	if err := writeJSON(&buf, actions); err != nil || buf.String() != "[]\n" {
+		t.Errorf("expected an empty list, got %q %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := writeJSON(&buf, []webapi.ActionType{{ActionID: 7, System: "a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "[\n  {") || !strings.HasSuffix(buf.String(), "]\n") {
+		t.Errorf("expected indented JSON, got %q", buf.String())
+	}
 }
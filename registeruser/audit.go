@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"registersystem/webapi"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// policySuffix is the suffix of the read policy of a group.
+const policySuffix = "_read_policy"
+
+// inventoryType are the names of the pieces of all groups in the SUSE Manager and in Vault.
+type inventoryType struct {
+	Mounts       []string // groups of the KV stores
+	Roles        []string
+	Policies     []string
+	Users        []string
+	SystemGroups []string
+}
+
+// tenantStateType reports, which pieces of a group exist.
+type tenantStateType struct {
+	Group       string `json:"group"`
+	Mount       bool   `json:"mount"`
+	Role        bool   `json:"role"`
+	Policy      bool   `json:"policy"`
+	User        bool   `json:"user"`
+	SystemGroup bool   `json:"systemgroup"`
+}
+
+// pieces returns the names of the pieces of the group with the given existence.
+func (t tenantStateType) pieces(exist bool) (names []string) {
+	for _, p := range []struct {
+		name  string
+		exist bool
+	}{
+		{"role", t.Role},
+		{"policy", t.Policy},
+		{"user", t.User},
+		{"systemgroup", t.SystemGroup},
+	} {
+		if p.exist == exist {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// Missing returns the pieces, which are missing for a group with a KV store.
+func (t tenantStateType) Missing() []string {
+	if !t.Mount {
+		return nil
+	}
+	return t.pieces(false)
+}
+
+// Orphaned returns the pieces, which are left behind by a group without a KV store.
+func (t tenantStateType) Orphaned() []string {
+	if t.Mount {
+		return nil
+	}
+	return t.pieces(true)
+}
+
+// Status summarizes the missing or orphaned pieces of the group.
+func (t tenantStateType) Status() string {
+	if missing := t.Missing(); len(missing) > 0 {
+		return fmt.Sprintf("missing: %s", strings.Join(missing, ", "))
+	}
+	if orphaned := t.Orphaned(); len(orphaned) > 0 {
+		return fmt.Sprintf("orphaned: %s", strings.Join(orphaned, ", "))
+	}
+	return "ok"
+}
+
+// correlateTenants groups the pieces by group. Only KV stores, AppRoles and read policies make a group, SUSE Manager
+// users and SystemGroups are only matched against them, so users which do not belong to a group are never reported.
+// AppRoles and read policies of other environments and the groups in exclude are skipped.
+func correlateTenants(inv inventoryType, exclude map[string]bool) []tenantStateType {
+
+	set := func(names []string) map[string]bool {
+		m := make(map[string]bool)
+		for _, n := range names {
+			m[n] = true
+		}
+		return m
+	}
+
+	mounts, users, systemgroups := set(inv.Mounts), set(inv.Users), set(inv.SystemGroups)
+	roles := make(map[string]bool)
+	for _, r := range inv.Roles {
+		if g, ok := groupOfRole(r); ok {
+			roles[g] = true
+		}
+	}
+	policies := make(map[string]bool)
+	for _, p := range inv.Policies {
+		if !strings.HasSuffix(p, policySuffix) || p == policySuffix {
+			continue
+		}
+		if g, ok := groupOfRole(strings.TrimSuffix(p, policySuffix)); ok {
+			policies[g] = true
+		}
+	}
+
+	groups := make(map[string]bool)
+	for _, m := range []map[string]bool{mounts, roles, policies} {
+		for g := range m {
+			if !exclude[g] {
+				groups[g] = true
+			}
+		}
+	}
+
+	var tenants []tenantStateType
+	for g := range groups {
+		tenants = append(tenants, tenantStateType{
+			Group:       g,
+			Mount:       mounts[g],
+			Role:        roles[g],
+			Policy:      policies[g],
+			User:        users[g],
+			SystemGroup: systemgroups[g],
+		})
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Group < tenants[j].Group })
+
+	return tenants
+}
+
+// scopeInventory keeps the AppRoles and read policies, which belong to the environment. A piece of a group with a KV
+// store belongs to it by name. A leftover read policy only belongs to it, if it grants the KV store of the group, and
+// a leftover AppRole only, if it carries such a policy, so the pieces of other applications are never a group.
+func scopeInventory(inv inventoryType, policyRules func(name string) (string, error), rolePolicies func(name string) ([]string, error)) (inventoryType, error) {
+
+	mounts := make(map[string]bool)
+	for _, m := range inv.Mounts {
+		mounts[m] = true
+	}
+
+	scoped := inv
+	scoped.Policies = nil
+	tied := make(map[string]bool)
+	for _, p := range inv.Policies {
+		if !strings.HasSuffix(p, policySuffix) || p == policySuffix {
+			continue
+		}
+		g, ok := groupOfRole(strings.TrimSuffix(p, policySuffix))
+		if !ok {
+			continue
+		}
+		if !mounts[g] {
+			rules, err := policyRules(p)
+			if err != nil {
+				return inv, err
+			}
+			grant := regexp.MustCompile(`"` + regexp.QuoteMeta(kvprefix+g) + `[/*"]`)
+			if !grant.MatchString(rules) {
+				continue
+			}
+		}
+		tied[g] = true
+		scoped.Policies = append(scoped.Policies, p)
+	}
+
+	scoped.Roles = nil
+	for _, r := range inv.Roles {
+		g, ok := groupOfRole(r)
+		if !ok {
+			continue
+		}
+		if !mounts[g] {
+			if !tied[g] {
+				continue
+			}
+			policies, err := rolePolicies(r)
+			if err != nil {
+				return inv, err
+			}
+			carries := false
+			for _, p := range policies {
+				carries = carries || p == roleName(g)+policySuffix
+			}
+			if !carries {
+				continue
+			}
+		}
+		scoped.Roles = append(scoped.Roles, r)
+	}
+
+	return scoped, nil
+}
+
+// vaultInventory returns the KV stores and the AppRoles and read policies of the environment. The pieces of other
+// applications are scoped out, see scopeInventory.
+func vaultInventory(client *api.Client) (inv inventoryType, err error) {
+	inv.Mounts, err = webapi.VaultListTenants(client, kvprefix, adminmount, verbose)
+	if err != nil {
+		return inv, fmt.Errorf("error listing groups: %v", err)
+	}
+	inv.Roles, err = webapi.VaultListRoles(client, verbose)
+	if err != nil {
+		return inv, fmt.Errorf("error listing roles: %v", err)
+	}
+	inv.Policies, err = webapi.VaultListPolicies(client, verbose)
+	if err != nil {
+		return inv, fmt.Errorf("error listing policies: %v", err)
+	}
+
+	inv, err = scopeInventory(inv, func(name string) (string, error) {
+		return webapi.VaultReadPolicy(client, name, verbose)
+	}, func(name string) ([]string, error) {
+		role, _, err := webapi.VaultReadRole(client, name, verbose)
+		return role.Policies, err
+	})
+	if err != nil {
+		return inv, fmt.Errorf("error reading the pieces of the groups: %v", err)
+	}
+	return inv, nil
+}
+
+// unmatchedPieces returns the SUSE Manager users and SystemGroups, which belong to no group and are not excluded.
+func unmatchedPieces(inv inventoryType, tenants []tenantStateType, exclude map[string]bool) (users, systemgroups []string) {
+
+	groups := make(map[string]bool)
+	for _, t := range tenants {
+		groups[t.Group] = true
+	}
+
+	for _, u := range inv.Users {
+		if !groups[u] && !exclude[u] {
+			users = append(users, u)
+		}
+	}
+	for _, sg := range inv.SystemGroups {
+		if !groups[sg] && !exclude[sg] {
+			systemgroups = append(systemgroups, sg)
+		}
+	}
+	sort.Strings(users)
+	sort.Strings(systemgroups)
+
+	return users, systemgroups
+}
+
+// adminExclude returns the groups, which are no tenants: the admin role of the token, the group of the admin KV
+// store, the SUMA admin and the accounts of the -exclude flag.
+func adminExclude(client *api.Client, sumalogin string) (map[string]bool, error) {
+	adminrole, err := webapi.VaultTokenRole(client, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := map[string]bool{sumalogin: true}
+	for _, e := range parseList(excludes, nil) {
+		exclude[e] = true
+	}
+	if g, ok := groupOfRole(adminrole); ok && adminrole != "" {
+		exclude[g] = true
+	}
+	if strings.HasPrefix(adminmount, kvprefix) {
+		exclude[strings.TrimPrefix(adminmount, kvprefix)] = true
+	}
+	return exclude, nil
+}
+
+// writeAudit prints the pieces and the status of the groups.
+func writeAudit(w io.Writer, tenants []tenantStateType) error {
+	yesno := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tKV\tROLE\tPOLICY\tUSER\tSYSTEMGROUP\tSTATUS")
+	for _, t := range tenants {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Group, yesno(t.Mount), yesno(t.Role), yesno(t.Policy), yesno(t.User), yesno(t.SystemGroup), t.Status())
+	}
+	return tw.Flush()
+}
+
+// writeUnmatched prints the SUSE Manager users and SystemGroups, which belong to no group. They are never fixed.
+func writeUnmatched(w io.Writer, users, systemgroups []string) error {
+	if len(users) > 0 {
+		if _, err := fmt.Fprintf(w, "SUMA users without group: %s\n", strings.Join(users, ", ")); err != nil {
+			return err
+		}
+	}
+	if len(systemgroups) > 0 {
+		if _, err := fmt.Fprintf(w, "SUMA systemgroups without group: %s\n", strings.Join(systemgroups, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixTenant recreates the missing pieces of a group with a KV store and removes the orphaned pieces of a group
+// without a KV store. The network of a recreated group is read from the config in the KV store.
+func fixTenant(client *api.Client, sessioncookie, sumaurl, policyTemplate string, t tenantStateType) error {
+
+	if !t.Mount {
+		if t.Role {
+			if err := webapi.VaultRemoveRole(client, roleName(t.Group), verbose); err != nil {
+				return err
+			}
+		}
+		if t.Policy {
+			if err := webapi.VaultDeletePolicy(client, roleName(t.Group), verbose); err != nil {
+				return err
+			}
+		}
+		// the SystemGroup is removed with the user
+		if t.User {
+			return webapi.SumaRemoveUser(sessioncookie, t.Group, sumaurl, verbose)
+		}
+		if t.SystemGroup {
+			return webapi.SumaRemoveSystemGroup(sessioncookie, sumaurl, t.Group, verbose)
+		}
+		return nil
+	}
+
+	path := kvprefix + t.Group
+	config, err := webapi.VaultGetSecrets(client, vaultAddress, path, "config", verbose)
+	if err != nil || config["network"] == nil || config["network"] == "" {
+		return fmt.Errorf("network of group %s not definied", t.Group)
+	}
+	groupnetwork := fmt.Sprintf("%s", config["network"])
+
+	if !t.Policy {
+		policy, err := groupPolicy(policyTemplate, t.Group, groupnetwork)
+		if err != nil {
+			return err
+		}
+		if _, err := webapi.VaultCreatePolicy(client, roleName(t.Group), policy, verbose); err != nil {
+			return err
+		}
+	}
+
+	if !t.Role {
+		options, err := roleOptions(groupnetwork, false)
+		if err != nil {
+			return err
+		}
+		roleID, secretID, wrapToken, err := webapi.VaultCreateRole(client, roleName(t.Group), roleName(t.Group)+policySuffix, options, wrapttl, verbose)
+		if err != nil {
+			return err
+		}
+		if err := webapi.VaultUpdateSecret(client, path+"/data/approle_output", "role_id", roleID, verbose); err != nil {
+			return err
+		}
+		// a wrapped secret ID is only known to the receiver of the wrapping token
+		if wrapToken != "" {
+			err = webapi.VaultRemoveSecretKey(client, path+"/data/approle_output", "secret_id", verbose)
+		} else {
+			err = webapi.VaultUpdateSecret(client, path+"/data/approle_output", "secret_id", secretID, verbose)
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeLoginInformation(os.Stdout, t.Group, roleID, secretID, wrapToken, wrapttl); err != nil {
+			return err
+		}
+	}
+
+	if !t.SystemGroup {
+		if err := webapi.SumaCreateSystemGroup(sessioncookie, sumaurl, t.Group, verbose); err != nil {
+			return err
+		}
+	}
+
+	// the password of a recreated user is only known to the KV store of the group
+	if !t.User {
+		password, err := generatePassword(passwordLength)
+		if err != nil {
+			return err
+		}
+		result, err := webapi.SumaAddUser(sessioncookie, t.Group, password, sumaurl, verbose)
+		if err == nil && result != http.StatusOK {
+			err = fmt.Errorf("got http error %d", result)
+		}
+		if err != nil {
+			return err
+		}
+		if err := webapi.VaultUpdateSecret(client, path+"/data/suma", "login", t.Group, verbose); err != nil {
+			return err
+		}
+		if err := webapi.VaultUpdateSecret(client, path+"/data/suma", "password", password, verbose); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "SUMA password of %s stored in %s/suma\n", t.Group, path)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCorrelateTenants(t *testing.T) {
+	inv := inventoryType{
+		Mounts:       []string{"alpha", "beta", "gamma"},
+		Roles:        []string{"admin", "alpha", "beta", "delta"},
+		Policies:     []string{"default", "root", "alpha_read_policy", "delta_read_policy", "dagobah_read_policy"},
+		Users:        []string{"admin", "alpha", "delta", "operator"},
+		SystemGroups: []string{"alpha", "beta", "production"},
+	}
+	exclude := map[string]bool{"admin": true, "dagobah": true}
+
+	tenants := correlateTenants(inv, exclude)
+
+	want := map[string]string{
+		"alpha": "ok",
+		"beta":  "missing: policy, user",
+		"gamma": "missing: role, policy, user, systemgroup",
+		"delta": "orphaned: role, policy, user",
+	}
+	if len(tenants) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), tenants)
+	}
+	for i, tenant := range tenants {
+		if i > 0 && tenants[i-1].Group > tenant.Group {
+			t.Errorf("groups not sorted: %+v", tenants)
+		}
+		if got := tenant.Status(); got != want[tenant.Group] {
+			t.Errorf("%s: got status %q; want %q", tenant.Group, got, want[tenant.Group])
+		}
+	}
+}
+
+func TestCorrelateTenants_Env(t *testing.T) {
+	origEnv := env
+	defer func() { env = origEnv }()
+	env = "lab2"
+
+	inv := inventoryType{
+		Mounts:       []string{"alpha"},
+		Roles:        []string{"alpha", "lab2-alpha", "lab2-delta"},
+		Policies:     []string{"alpha_read_policy", "lab2-alpha_read_policy"},
+		Users:        []string{"alpha", "delta"},
+		SystemGroups: []string{"alpha"},
+	}
+
+	tenants := correlateTenants(inv, nil)
+
+	if len(tenants) != 2 || tenants[0].Group != "alpha" || tenants[0].Status() != "ok" ||
+		tenants[1].Group != "delta" || tenants[1].Status() != "orphaned: role, user" {
+		t.Errorf("expected only the AppRoles and policies of the environment, got %+v", tenants)
+	}
+}
+
+func TestScopeInventory(t *testing.T) {
+	origKVPrefix, origEnv := kvprefix, env
+	defer func() { kvprefix, env = origKVPrefix, origEnv }()
+	kvprefix, env = "kv-clab-", ""
+
+	rules := map[string]string{
+		"delta_read_policy":  `path "kv-clab-delta*" {}`,
+		"deltax_read_policy": `path "kv-clab-delta*" {}`,
+		"app_read_policy":    `path "secret/app/*" {}`,
+	}
+	policies := map[string][]string{
+		"delta":   {"default", "delta_read_policy"},
+		"app":     {"app_read_policy"},
+		"jenkins": {"jenkins"},
+	}
+	inv := inventoryType{
+		Mounts:   []string{"alpha"},
+		Roles:    []string{"alpha", "delta", "app", "jenkins"},
+		Policies: []string{"default", "alpha_read_policy", "delta_read_policy", "deltax_read_policy", "app_read_policy"},
+	}
+
+	scoped, err := scopeInventory(inv, func(name string) (string, error) {
+		return rules[name], nil
+	}, func(name string) ([]string, error) {
+		return policies[name], nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(scoped.Roles, ",") != "alpha,delta" {
+		t.Errorf("expected only the AppRoles of the environment, got %v", scoped.Roles)
+	}
+	if strings.Join(scoped.Policies, ",") != "alpha_read_policy,delta_read_policy" {
+		t.Errorf("expected only the policies of the environment, got %v", scoped.Policies)
+	}
+
+	if _, err := scopeInventory(inv, func(name string) (string, error) {
+		return "", errors.New("permission denied")
+	}, nil); err == nil {
+		t.Error("expected error of an unreadable policy, got nil")
+	}
+}
+
+func TestUnmatchedPieces(t *testing.T) {
+	inv := inventoryType{
+		Users:        []string{"operator", "alpha", "admin", "backup"},
+		SystemGroups: []string{"production", "alpha"},
+	}
+	tenants := []tenantStateType{{Group: "alpha", Mount: true}}
+
+	users, systemgroups := unmatchedPieces(inv, tenants, map[string]bool{"admin": true, "backup": true})
+	if strings.Join(users, ",") != "operator" || strings.Join(systemgroups, ",") != "production" {
+		t.Errorf("unexpected unmatched pieces: %v %v", users, systemgroups)
+	}
+
+	var buf bytes.Buffer
+	if err := writeUnmatched(&buf, users, systemgroups); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "SUMA users without group: operator\nSUMA systemgroups without group: production\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestTenantStateMissingOrphaned(t *testing.T) {
+	complete := tenantStateType{Group: "alpha", Mount: true, Role: true, Policy: true, User: true, SystemGroup: true}
+	if complete.Missing() != nil || complete.Orphaned() != nil {
+		t.Errorf("expected complete group, got missing %v orphaned %v", complete.Missing(), complete.Orphaned())
+	}
+
+	orphan := tenantStateType{Group: "alpha", SystemGroup: true}
+	if orphan.Missing() != nil || strings.Join(orphan.Orphaned(), ",") != "systemgroup" {
+		t.Errorf("unexpected orphaned pieces: %v", orphan.Orphaned())
+	}
+}
+
+func TestWriteAudit(t *testing.T) {
+	var buf bytes.Buffer
+	tenants := []tenantStateType{
+		{Group: "alpha", Mount: true, Role: true, Policy: true, User: true, SystemGroup: true},
+		{Group: "delta", Role: true},
+	}
+
+	if err := writeAudit(&buf, tenants); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 groups, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "GROUP") || !strings.HasSuffix(lines[1], "ok") || !strings.HasSuffix(lines[2], "orphaned: role") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
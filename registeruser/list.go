@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"registersystem/webapi"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// createdAtKey is the key of the creation time of the group in the config of the group.
+const createdAtKey = "created_at"
+
+// tenantInfoType is a group in the inventory of the groups.
+type tenantInfoType struct {
+	Group       string   `json:"group"`
+	Network     string   `json:"network"`
+	TokenCIDRs  []string `json:"token_bound_cidrs"`
+	TokenTTL    string   `json:"token_ttl"`
+	TokenMaxTTL string   `json:"token_max_ttl"`
+	TokenType   string   `json:"token_type"`
+	SumaUser    string   `json:"suma_user"`
+	Systems     *int     `json:"systems"`
+	CreatedAt   string   `json:"created_at"`
+	Status      string   `json:"status"`
+}
+
+// tenantInfo collects the inventory of the group from its config, its role, the SUMA users by enabled state and
+// the system counts of the SystemGroups. Unknown values are empty, Systems is nil without a SystemGroup. A zero TTL of
+// the role is the default TTL of Vault and reported as "default".
+func tenantInfo(group string, config map[string]interface{}, role webapi.RoleOptionsType, roleFound bool, users map[string]bool, counts map[string]int) tenantInfoType {
+
+	info := tenantInfoType{Group: group, SumaUser: "missing"}
+
+	value := func(key string) string {
+		if config[key] == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s", config[key])
+	}
+	info.Network = value("network")
+	info.CreatedAt = value(createdAtKey)
+
+	ttl := func(d time.Duration) string {
+		if d == 0 {
+			return "default"
+		}
+		return d.String()
+	}
+
+	if roleFound {
+		info.TokenCIDRs = role.TokenBoundCIDRs
+		info.TokenTTL = ttl(role.TokenTTL)
+		info.TokenMaxTTL = ttl(role.TokenMaxTTL)
+		info.TokenType = role.TokenType
+	}
+
+	if enabled, ok := users[group]; ok {
+		info.SumaUser = "disabled"
+		if enabled {
+			info.SumaUser = "enabled"
+		}
+	}
+
+	if count, ok := counts[group]; ok {
+		info.Systems = &count
+	}
+
+	return info
+}
+
+// writeTenants prints the inventory of the groups as table.
+func writeTenants(w io.Writer, tenants []tenantInfoType) error {
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tNETWORK\tTOKEN CIDRS\tTOKEN TTL\tTOKEN MAX TTL\tTOKEN TYPE\tSUMA USER\tSYSTEMS\tCREATED\tSTATUS")
+	for _, t := range tenants {
+		systems := "-"
+		if t.Systems != nil {
+			systems = fmt.Sprintf("%d", *t.Systems)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Group, dash(t.Network), dash(strings.Join(t.TokenCIDRs, ",")),
+			dash(t.TokenTTL), dash(t.TokenMaxTTL), dash(t.TokenType), t.SumaUser, systems, dash(t.CreatedAt), dash(t.Status))
+	}
+	return tw.Flush()
+}
+
+// writeTenantsJSON prints the inventory of the groups as JSON.
+func writeTenantsJSON(w io.Writer, tenants []tenantInfoType) error {
+	if tenants == nil {
+		tenants = []tenantInfoType{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tenants)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"registersystem/webapi"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTenantInfo(t *testing.T) {
+	config := map[string]interface{}{"network": "172.1.22.0", createdAtKey: "2026-10-18T12:00:00Z"}
+	role := webapi.RoleOptionsType{TokenTTL: time.Hour, TokenMaxTTL: 4 * time.Hour, TokenType: "default", TokenBoundCIDRs: []string{"172.1.22.0/24"}}
+	users := map[string]bool{"alpha": true, "beta": false}
+	counts := map[string]int{"alpha": 3}
+
+	info := tenantInfo("alpha", config, role, true, users, counts)
+	if info.Network != "172.1.22.0" || info.CreatedAt != "2026-10-18T12:00:00Z" {
+		t.Errorf("unexpected config values: %+v", info)
+	}
+	if info.TokenTTL != "1h0m0s" || info.TokenMaxTTL != "4h0m0s" || info.TokenType != "default" || len(info.TokenCIDRs) != 1 {
+		t.Errorf("unexpected role values: %+v", info)
+	}
+	if info.SumaUser != "enabled" || info.Systems == nil || *info.Systems != 3 {
+		t.Errorf("unexpected SUMA values: %+v", info)
+	}
+
+	info = tenantInfo("beta", nil, webapi.RoleOptionsType{}, false, users, counts)
+	if info.Network != "" || info.TokenTTL != "" || info.SumaUser != "disabled" || info.Systems != nil {
+		t.Errorf("unexpected values without config, role and SystemGroup: %+v", info)
+	}
+
+	if info := tenantInfo("gamma", nil, webapi.RoleOptionsType{}, false, users, counts); info.SumaUser != "missing" {
+		t.Errorf("expected missing SUMA user, got %q", info.SumaUser)
+	}
+}
+
+func TestWriteTenants(t *testing.T) {
+	three := 3
+	tenants := []tenantInfoType{
+		{Group: "alpha", Network: "172.1.22.0", TokenCIDRs: []string{"172.1.22.0/24"}, TokenTTL: "1h0m0s", TokenMaxTTL: "4h0m0s", TokenType: "default", SumaUser: "enabled", Systems: &three, CreatedAt: "2026-10-18T12:00:00Z", Status: "ok"},
+		{Group: "beta", SumaUser: "missing", Status: "missing: role, policy, user"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTenants(&buf, tenants); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "GROUP") {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 10 || fields[7] != "3" || fields[9] != "ok" {
+		t.Errorf("unexpected line: %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) < 10 || fields[6] != "missing" || fields[7] != "-" || !strings.HasSuffix(lines[2], "missing: role, policy, user") {
+		t.Errorf("unexpected line: %q", lines[2])
+	}
+
+	buf.Reset()
+	if err := writeTenantsJSON(&buf, tenants); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got) != 2 || got[0]["systems"] != float64(3) || got[1]["systems"] != nil || got[0][createdAtKey] != "2026-10-18T12:00:00Z" || got[1]["status"] != "missing: role, policy, user" {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeTenantsJSON(&buf, nil); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected empty list, got %q %v", buf.String(), err)
+	}
+}
+
+func TestWriteTenants_DefaultTTL(t *testing.T) {
+	tenants := []tenantInfoType{
+		tenantInfo("alpha", nil, webapi.RoleOptionsType{TokenType: "service"}, true, map[string]bool{"alpha": true}, nil),
+	}
+
+	var buf bytes.Buffer
+	if err := writeTenants(&buf, tenants); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[1]); len(fields) != 10 || fields[3] != "default" || fields[4] != "default" {
+		t.Errorf("expected default TTLs, got %q", lines[1])
+	}
+
+	buf.Reset()
+	if err := writeTenantsJSON(&buf, tenants); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got[0]["token_ttl"] != "default" || got[0]["token_max_ttl"] != "default" {
+		t.Errorf("expected default TTLs in JSON, got %s", buf.String())
+	}
+}
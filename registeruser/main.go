@@ -6,48 +6,116 @@ package main
 */
 
 import (
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
-	"net/http"
 	"net/url"
 	"os"
 	"registersystem/webapi"
 	"strings"
+	"time"
 )
 
 var (
 	// commandline flags
-	verbose       bool
-	roleID        string
-	secretID      string
-	group         string
-	grouppassword string
-	network       string
-	vaultAddress  string
-	task          string
+	verbose        bool
+	roleID         string
+	secretID       string
+	group          string
+	grouppassword  string
+	network        string
+	basechannel    string
+	childchannels  string
+	configchannels string
+	formulafile    string
+	days           int
+	yes            bool
+	deletesystems  bool
+	reportfile     string
+	genpass        bool
+	showpass       bool
+	destroyold     bool
+	fix            bool
+	jsonOutput     bool
+	wrapttl        time.Duration
+	secretidttl    time.Duration
+	secretiduses   int
+	secretidcidrs  string
+	tokencidrs     string
+	tokenttl       time.Duration
+	tokenmaxttl    time.Duration
+	tokentype      string
+	period         time.Duration
+	policies       string
+	policytemplate string
+	kvprefix       string
+	adminmount     string
+	adminpath      string
+	env            string
+	excludes       string
+	vaultAddress   string
+	task           string
 
 	grouproleID   string // roleID of the created User
 	groupsecretID string // secretID of the created User
-)
 
-const kvprefix string = "kv-clab-"
+	groupwraptoken string // response-wrapping token of the secretID of the created User
+)
 
 func registerFlags(fs *flag.FlagSet) {
 	fs.StringVar(&roleID, "r", "", "HCV roleID")
 	fs.StringVar(&secretID, "s", "", "HCV secretID")
 	fs.StringVar(&group, "g", "", "SUSE Manager Group")
 	fs.StringVar(&grouppassword, "d", "", "SUSE Manager Group Password")
+	fs.BoolVar(&genpass, "genpass", false, "Generate the SUSE Manager Group Password of a new user instead of -d and store it in the KV store of the group")
+	fs.BoolVar(&showpass, "showpass", false, "Print the generated SUSE Manager Group Password")
 	fs.StringVar(&network, "n", "", "Network of the Testenvironment f.i. 172.1.22.0")
+	fs.StringVar(&basechannel, "basechannel", "", "Base channel label of the activation key and the systems of the group (default SUSE Manager default)")
+	fs.StringVar(&childchannels, "childchannels", "", "Comma separated list of child channel labels of the systems of the group")
+	fs.StringVar(&configchannels, "configchannels", "", "Comma separated list of configuration channel labels of the systems of the group in ranking order")
+	fs.StringVar(&formulafile, "formulas", "", "JSON file with the formulas and their pillar data of the group, f.i. {\"formulas\": [{\"name\": \"locale\", \"data\": {...}}]}")
+	fs.IntVar(&days, "days", 0, "With task reap, also reap systems not checked in for the number of days (default only expired systems)")
+	fs.BoolVar(&yes, "yes", false, "With task reap or audit -fix, do not ask for confirmation")
+	fs.BoolVar(&deletesystems, "delete", false, "With task reap, delete the systems from the SUSE Manager instead of removing them from the group")
+	fs.StringVar(&reportfile, "report", "", "With task reap, write a JSON report of the reaped systems to the file")
+	fs.DurationVar(&secretidttl, "secretidttl", 0, "TTL of the secret IDs of the group, f.i. 720h (default unlimited)")
+	fs.IntVar(&secretiduses, "secretiduses", 0, "Number of logins with one secret ID of the group (default unlimited)")
+	fs.StringVar(&secretidcidrs, "secretidcidrs", "", "Comma separated list of CIDRs, from which the secret IDs of the group could be used, or none (default the network of the group)")
+	fs.StringVar(&tokencidrs, "tokencidrs", "", "Comma separated list of CIDRs, from which the tokens of the group could be used, or none (default the network of the group)")
+	fs.DurationVar(&tokenttl, "tokenttl", 0, "TTL of the tokens of the group, f.i. 30m (default 1h)")
+	fs.DurationVar(&tokenmaxttl, "tokenmaxttl", 0, "Maximum TTL of the tokens of the group, f.i. 8h (default 4h)")
+	fs.StringVar(&tokentype, "tokentype", "", "Type of the tokens of the group, service or batch (default service)")
+	fs.DurationVar(&period, "period", 0, "Period of the tokens of the group, f.i. 24h (default none)")
+	fs.StringVar(&policies, "policies", "", "Comma separated list of additional policies of the group, or none with task update to remove them")
+	fs.StringVar(&policytemplate, "policytemplate", "", "Go text/template file of the read policy of the group with the variables .Group, .Role, .Network, .KVPrefix, .AdminMount and .AdminPath (default built-in policy)")
+	fs.StringVar(&excludes, "exclude", "", "With task audit, comma separated list of SUSE Manager admin accounts and Systemgroups, which are no groups")
+	fs.BoolVar(&fix, "fix", false, "With task audit, recreate the missing pieces of the groups and remove the orphaned pieces")
+	fs.BoolVar(&destroyold, "destroyold", false, "With task rotate, destroy all previous secret IDs of the group")
+	fs.DurationVar(&wrapttl, "wrapttl", 0, "With task add or rotate, return the new secret ID only as response-wrapping token valid for the TTL, f.i. 15m")
+	fs.StringVar(&kvprefix, "kvprefix", webapi.DefaultKVPrefix, "Prefix of the mounts of the KV stores of the groups")
+	fs.StringVar(&adminmount, "adminmount", webapi.DefaultAdminMount, "Mount of the KV store with the SUSE Manager credentials")
+	fs.StringVar(&adminpath, "adminpath", webapi.DefaultAdminPath, "Path of the SUSE Manager credentials in the admin KV store")
+	fs.StringVar(&env, "env", "", "Name of the environment, which prefixes the AppRoles and policies of the groups, required with a kvprefix other than "+webapi.DefaultKVPrefix)
 	fs.StringVar(&vaultAddress, "a", "", "Vault Address")
-	fs.StringVar(&task, "t", "", "Task [add | delete]")
+	fs.StringVar(&task, "t", "", "Task [add | delete | formula | list | reap | audit | checkpolicy | rotate | update]")
+	fs.BoolVar(&jsonOutput, "json", false, "With task list, output in JSON format")
 	fs.BoolVar(&verbose, "v", false, "Verbose output")
 }
 
 func customUsage() {
-	fmt.Fprintf(os.Stderr, "Usage of %s: -r [roleID] -s [secretID] -a [URL Vault] -g [SUMA Group] -d [SUMA Grouppassword] -n [Network] -t [add|delete] -v [verbose]\n\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "The program create or delete an user und policy in HCV and create an user in the SUSE Manager.\n\nParameter:\n")
+	fmt.Fprintf(os.Stderr, "Usage of %s: -r [roleID] -s [secretID] -a [URL Vault] -g [SUMA Group] -d [SUMA Grouppassword | -genpass] -n [Network] -t [add|delete|formula|list|reap|audit|checkpolicy|rotate|update] -v [verbose]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "The program create or delete an user und policy in HCV and create an user and an activation key in the SUSE Manager.\n")
+	fmt.Fprintf(os.Stderr, "The task formula assigns the formulas of the -formulas file to the Systemgroup and shows the formulas of the Systemgroup.\n")
+	fmt.Fprintf(os.Stderr, "The task list shows the network, the token options, the SUMA user, the number of systems and the creation time of the groups.\n")
+	fmt.Fprintf(os.Stderr, "The task reap removes the expired systems of the group or, without -g, of all groups.\n")
+	fmt.Fprintf(os.Stderr, "The task audit reports the missing or orphaned SUMA users, Systemgroups, AppRoles, policies and KV stores of the groups.\n")
+	fmt.Fprintf(os.Stderr, "The task checkpolicy compares the policy of the group or, without -g, of all groups with the rendered policy template.\n")
+	fmt.Fprintf(os.Stderr, "The task rotate generates a new secret ID for the role of the group.\n")
+	fmt.Fprintf(os.Stderr, "The task update changes the token and secret ID options of the role of the group, options not given stay unchanged.\n\nParameter:\n")
 
 	flag.PrintDefaults()
 }
@@ -76,11 +144,264 @@ func getTask(line string) string {
 		return "add"
 	case "delete", "d":
 		return "delete"
+	case "formula", "f":
+		return "formula"
+	case "list", "l":
+		return "list"
+	case "reap":
+		return "reap"
+	case "audit":
+		return "audit"
+	case "checkpolicy":
+		return "checkpolicy"
+	case "rotate":
+		return "rotate"
+	case "update":
+		return "update"
 	default:
 		return "error"
 	}
 }
 
+// readFormulaSpec reads the formulas of the group from a JSON file.
+func readFormulaSpec(filename string) ([]webapi.FormulaType, error) {
+
+	type FormulaSpec struct {
+		Formulas []webapi.FormulaType `json:"formulas"`
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec FormulaSpec
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", filename, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range spec.Formulas {
+		if isEmpty(f.Name) {
+			return nil, fmt.Errorf("formula without name in %s", filename)
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("formula %s defined twice in %s", f.Name, filename)
+		}
+		seen[f.Name] = true
+	}
+
+	return spec.Formulas, nil
+}
+
+func writeFormulas(w io.Writer, group string, formulas []webapi.FormulaType) error {
+
+	if len(formulas) == 0 {
+		_, err := fmt.Fprintf(w, "No formulas assigned to group %s\n", group)
+		return err
+	}
+
+	for _, f := range formulas {
+		if len(f.Data) == 0 {
+			if _, err := fmt.Fprintf(w, "Formula: %s\n", f.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.MarshalIndent(f.Data, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "Formula: %s\n  %s\n", f.Name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reapResultType is the result of reaping a stale system.
+type reapResultType struct {
+	webapi.StaleSystemType
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// confirm asks the question and reports, if the answer is yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func writeStaleSystems(w io.Writer, stale []webapi.StaleSystemType) error {
+	for _, s := range stale {
+		if _, err := fmt.Fprintf(w, "%s: %s (%s) %s\n", s.Group, s.Name, s.IP, s.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReapReport(filename string, results []reapResultType) error {
+	if results == nil {
+		results = []reapResultType{}
+	}
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(out, '\n'), 0644)
+}
+
+// parseList returns the entries of the comma separated list, def for an empty list and an empty list for none.
+func parseList(line string, def []string) []string {
+	if isEmpty(line) {
+		return def
+	}
+	if strings.ToLower(line) == "none" {
+		return []string{}
+	}
+
+	entries := []string{}
+	for _, e := range strings.Split(line, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// parseCIDRs returns the CIDRs of the comma separated list, def for an empty list and no CIDRs for none.
+func parseCIDRs(line string, def []string) ([]string, error) {
+	cidrs := parseList(line, def)
+	for _, c := range cidrs {
+		if _, _, err := net.ParseCIDR(c); err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", c)
+		}
+	}
+	return cidrs, nil
+}
+
+// roleOptions returns the options of the role of the group from the commandline flags. On update, options not
+// given on the commandline stay unchanged, else the CIDRs default to the network of the group.
+func roleOptions(network string, update bool) (options webapi.RoleOptionsType, err error) {
+
+	options.SecretIDTTL = secretidttl
+	options.SecretIDNumUses = secretiduses
+	options.TokenTTL = tokenttl
+	options.TokenMaxTTL = tokenmaxttl
+	options.Period = period
+
+	switch strings.ToLower(tokentype) {
+	case "":
+	case "service", "batch", "default":
+		options.TokenType = strings.ToLower(tokentype)
+	default:
+		return options, fmt.Errorf("tokentype: invalid type %q, use service or batch", tokentype)
+	}
+
+	if options.TokenMaxTTL > 0 && options.TokenTTL > options.TokenMaxTTL {
+		return options, fmt.Errorf("tokenttl %v exceeds tokenmaxttl %v", options.TokenTTL, options.TokenMaxTTL)
+	}
+
+	var def []string
+	if !update {
+		def = []string{webapi.NetworkCIDR(network)}
+	}
+
+	options.SecretIDBoundCIDRs, err = parseCIDRs(secretidcidrs, def)
+	if err != nil {
+		return options, fmt.Errorf("secretidcidrs: %v", err)
+	}
+
+	options.TokenBoundCIDRs, err = parseCIDRs(tokencidrs, def)
+	if err != nil {
+		return options, fmt.Errorf("tokencidrs: %v", err)
+	}
+
+	options.Policies = parseList(policies, nil)
+
+	return options, nil
+}
+
+// roleName returns the name of the AppRole of the group in the environment. The read policy of the group is the role
+// name with the policySuffix.
+func roleName(group string) string {
+	if isEmpty(env) {
+		return group
+	}
+	return env + "-" + group
+}
+
+// groupOfRole returns the group of the AppRole or read policy name without the policySuffix and reports, if the name
+// belongs to the environment.
+func groupOfRole(name string) (string, bool) {
+	if isEmpty(env) {
+		return name, true
+	}
+	if !strings.HasPrefix(name, env+"-") || name == env+"-" {
+		return "", false
+	}
+	return strings.TrimPrefix(name, env+"-"), true
+}
+
+// groupPolicy renders the read policy of the group from the policy template.
+func groupPolicy(policyTemplate, group, network string) (string, error) {
+	return webapi.RenderPolicy(policyTemplate, webapi.PolicyDataType{
+		Group:      group,
+		Role:       roleName(group),
+		Network:    network,
+		KVPrefix:   kvprefix,
+		AdminMount: adminmount,
+		AdminPath:  adminpath,
+	})
+}
+
+// checkPolicy prints, if the live policy of the group differs from the rendered policy, and reports if it matches.
+func checkPolicy(w io.Writer, group, rendered, live string) bool {
+	if live == "" {
+		fmt.Fprintf(w, "policy of group %s is missing\n", group)
+		return false
+	}
+
+	diff := webapi.PolicyDiff(rendered, live)
+	if len(diff) == 0 {
+		fmt.Fprintf(w, "policy of group %s is up to date\n", group)
+		return true
+	}
+
+	fmt.Fprintf(w, "policy of group %s differs from the template:\n", group)
+	for _, line := range diff {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return false
+}
+
+// writeLoginInformation prints the roleID and the secretID or the response-wrapping token of the secretID.
+func writeLoginInformation(w io.Writer, group, roleID, secretID, wrapToken string, ttl time.Duration) error {
+	if wrapToken != "" {
+		_, err := fmt.Fprintf(w, "API Login-Information for User: %s\nroleID=%s\nwrappingToken=%s\nThe secretID is wrapped, unwrap it within %v with: vault unwrap <token>\n", group, roleID, wrapToken, ttl)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "API Login-Information for User: %s\nroleID=%s\nsecretID=%s\n", group, roleID, secretID)
+	return err
+}
+
+// writeSecretID prints the new secret ID or the response-wrapping token of the secret ID.
+func writeSecretID(w io.Writer, group, secretID, wrapToken string, ttl time.Duration) error {
+	if wrapToken != "" {
+		_, err := fmt.Fprintf(w, "Wrapped secretID for User: %s, unwrap within %v with: vault unwrap <token>\nwrappingToken=%s\n", group, ttl, wrapToken)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "New secretID for User: %s\nsecretID=%s\n", group, secretID)
+	return err
+}
+
 func checkFlag(proleID, psecretID, pgroup, pgrouppassword, pnetwork, pvault, ptask string) bool {
 
 	if isEmpty(proleID) {
@@ -93,12 +414,19 @@ func checkFlag(proleID, psecretID, pgroup, pgrouppassword, pnetwork, pvault, pta
 		return false
 	}
 
-	if isEmpty(pgroup) {
-		log.Println("Please enter a group (user) to create.")
-		return false
+	switch getTask(ptask) {
+	case "list", "reap", "audit", "checkpolicy":
+	default:
+		if isEmpty(pgroup) {
+			log.Println("Please enter a group (user) to create.")
+			return false
+		}
 	}
 
-	if isEmpty(pgrouppassword) {
+	// the password and the network of the group are only required to add or delete the group
+	groupTask := getTask(ptask) == "add" || getTask(ptask) == "delete"
+
+	if groupTask && isEmpty(pgrouppassword) {
 		log.Println("Please enter a password for the group (user) in SUSE Manager.")
 		return false
 	}
@@ -118,7 +446,7 @@ func checkFlag(proleID, psecretID, pgroup, pgrouppassword, pnetwork, pvault, pta
 		return false
 	}
 
-	if !isIP(pnetwork) || isEmpty(pnetwork) {
+	if groupTask && (!isIP(pnetwork) || isEmpty(pnetwork)) {
 		log.Println("Please enter a valid IP for the network.")
 		return false
 	}
@@ -137,8 +465,36 @@ func main() {
 		log.Println("DEBUG MAIN Parameter: roleID:", roleID)
 		log.Println("DEBUG MAIN Parameter: secretID:", secretID)
 		log.Println("DEBUG MAIN Parameter: group:", group)
-		log.Println("DEBUG MAIN Parameter: grouppassword:", grouppassword)
+		log.Println("DEBUG MAIN Parameter: genpass:", genpass)
+		log.Println("DEBUG MAIN Parameter: showpass:", showpass)
 		log.Println("DEBUG MAIN Parameter: network:", network)
+		log.Println("DEBUG MAIN Parameter: basechannel:", basechannel)
+		log.Println("DEBUG MAIN Parameter: childchannels:", childchannels)
+		log.Println("DEBUG MAIN Parameter: configchannels:", configchannels)
+		log.Println("DEBUG MAIN Parameter: formulas:", formulafile)
+		log.Println("DEBUG MAIN Parameter: days:", days)
+		log.Println("DEBUG MAIN Parameter: yes:", yes)
+		log.Println("DEBUG MAIN Parameter: delete:", deletesystems)
+		log.Println("DEBUG MAIN Parameter: report:", reportfile)
+		log.Println("DEBUG MAIN Parameter: secretidttl:", secretidttl)
+		log.Println("DEBUG MAIN Parameter: secretiduses:", secretiduses)
+		log.Println("DEBUG MAIN Parameter: secretidcidrs:", secretidcidrs)
+		log.Println("DEBUG MAIN Parameter: tokencidrs:", tokencidrs)
+		log.Println("DEBUG MAIN Parameter: tokenttl:", tokenttl)
+		log.Println("DEBUG MAIN Parameter: tokenmaxttl:", tokenmaxttl)
+		log.Println("DEBUG MAIN Parameter: tokentype:", tokentype)
+		log.Println("DEBUG MAIN Parameter: period:", period)
+		log.Println("DEBUG MAIN Parameter: policies:", policies)
+		log.Println("DEBUG MAIN Parameter: policytemplate:", policytemplate)
+		log.Println("DEBUG MAIN Parameter: kvprefix:", kvprefix)
+		log.Println("DEBUG MAIN Parameter: adminmount:", adminmount)
+		log.Println("DEBUG MAIN Parameter: adminpath:", adminpath)
+		log.Println("DEBUG MAIN Parameter: env:", env)
+		log.Println("DEBUG MAIN Parameter: destroyold:", destroyold)
+		log.Println("DEBUG MAIN Parameter: fix:", fix)
+		log.Println("DEBUG MAIN Parameter: exclude:", excludes)
+		log.Println("DEBUG MAIN Parameter: json:", jsonOutput)
+		log.Println("DEBUG MAIN Parameter: wrapttl:", wrapttl)
 		log.Println("DEBUG MAIN Parameter: vaultAddress:", vaultAddress)
 		log.Println("DEBUG MAIN Parameter: task:", task)
 	}
@@ -149,13 +505,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	if genpass {
+		if !isEmpty(grouppassword) {
+			log.Fatalf("please enter either a password with -d or -genpass.")
+		}
+		password, err := generatePassword(passwordLength)
+		if err != nil {
+			log.Fatalf("error generating password: %v", err)
+		}
+		grouppassword = password
+	}
+
 	if !checkFlag(roleID, secretID, group, grouppassword, network, vaultAddress, task) {
 		os.Exit(1)
 	}
 
+	if isEmpty(kvprefix) || isEmpty(adminmount) || isEmpty(adminpath) {
+		log.Fatalf("please enter the kvprefix, the adminmount and the adminpath.")
+	}
+
+	// AppRoles and policies are global in Vault, a second environment must not use the names of the first one
+	if kvprefix != webapi.DefaultKVPrefix && isEmpty(env) {
+		log.Fatalf("please enter the environment with -env for the kvprefix %s.", kvprefix)
+	}
+
 	task = getTask(task)
 	if task == "error" {
-		log.Fatalf("please enter a valid task [add | delete].\n")
+		log.Fatalf("please enter a valid task [add | delete | formula | list | reap | audit | checkpolicy | rotate | update].\n")
 	}
 
 	client, err := webapi.VaultLogin(roleID, secretID, vaultAddress, verbose)
@@ -165,7 +541,7 @@ func main() {
 
 	defer webapi.VaultLogout(client, verbose)
 
-	suma, err := webapi.VaultGetSecrets(client, vaultAddress, "dagobah", "suma", verbose)
+	suma, err := webapi.VaultGetSecrets(client, vaultAddress, adminmount, adminpath, verbose)
 	if err != nil {
 		log.Fatalf("error getting vault secrets: %v", err)
 	}
@@ -190,7 +566,7 @@ func main() {
 	case "add":
 		{
 
-			// create user in suma
+			// create user in suma and the role and KV store in vault, a failed step rolls back the previous steps
 			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
 			if err != nil {
 				log.Fatalf("error during SUMA login. Errorcode %v", err)
@@ -199,99 +575,461 @@ func main() {
 				log.Printf("DEBUG MAIN: Session Cookie for SUMA: %s\n", sessioncookie)
 			}
 
-			result, err := webapi.SumaAddUser(sessioncookie, group, grouppassword, sumaurl, verbose)
+			options, err := roleOptions(network, false)
 			if err != nil {
-				log.Fatalf("error adding user to SUMA. Errorcode %v", err)
+				log.Fatalf("error in role options: %v", err)
 			}
-			if result != http.StatusOK {
-				log.Printf("an error occured, got http error %d", result)
-				os.Exit(1)
+
+			policyTemplate, err := webapi.ReadPolicyTemplate(policytemplate)
+			if err != nil {
+				log.Fatalf("error reading policy template: %v", err)
+			}
+			policy, err := groupPolicy(policyTemplate, group, network)
+			if err != nil {
+				log.Fatalf("error rendering policy: %v", err)
+			}
+
+			var activationkey string
+			rollback, err := runSteps(onboardingSteps(client, sessioncookie, sumaurl, policy, options, &activationkey), verbose)
+			if err != nil {
+				log.Printf("error adding group %s: %v", group, err)
+				log.Fatalf("onboarding of group %s %s", group, rollback)
+			}
+
+			if err := writeLoginInformation(os.Stdout, group, grouproleID, groupsecretID, groupwraptoken, wrapttl); err != nil {
+				log.Fatalf("error writing login information: %v", err)
+			}
+			fmt.Fprintf(os.Stdout, "Activation key: %s\n", activationkey)
+			if genpass {
+				fmt.Fprintf(os.Stdout, "SUMA password stored in %s%s/suma\n", kvprefix, group)
+				if showpass {
+					fmt.Fprintf(os.Stdout, "SUMA password: %s\n", grouppassword)
+				}
+			}
+
+		}
+	case "delete":
+		{
+			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error during SUMA login. Errorcode %v", err)
+			}
+			if verbose {
+				log.Printf("DEBUG MAIN: Session Cookie for SUMA: %s\n", sessioncookie)
+			}
+
+			// remove activation key, the key is stored in the config of the group
+			config, err := webapi.VaultGetSecrets(client, vaultAddress, kvprefix+group, "config", verbose)
+			if err != nil || config["activationkey"] == nil || config["activationkey"] == "" {
+				log.Printf("no activation key found for group %s.\n", group)
 			} else {
-				if verbose {
-					log.Printf("successful add user %s, got result from %s: %d\n", group, sumaurl, result)
+				activationkey := fmt.Sprintf("%s", config["activationkey"])
+				err = webapi.SumaDeleteActivationKey(sessioncookie, sumaurl, activationkey, verbose)
+				if err != nil {
+					log.Printf("an error occured, got error %v", err)
+				} else {
+					log.Printf("activation key %s successfully removed from SUMA.\n", activationkey)
 				}
 			}
 
-			// do the vault stuff
-			policyName, err := webapi.VaultCreatePolicy(client, group, verbose)
+			err = webapi.SumaRemoveUser(sessioncookie, group, sumaurl, verbose)
 			if err != nil {
-				log.Fatalf("error create policy: %v", err)
+				log.Printf("an error occured, got error %v", err)
+			} else {
+				log.Printf("user %s successfully removed from SUMA.\n", group)
 			}
 
-			if verbose {
-				log.Printf("DEBUG MAIN: policyName = %s\n", policyName)
-				log.Printf("DEBUG MAIN: client =  %v\n", client)
+			err = webapi.VaultDeletePolicy(client, roleName(group), verbose)
+			if err != nil {
+				log.Fatalf("error deleting policy: %v", err)
 			}
 
-			grouproleID, groupsecretID, err = webapi.VaultCreateRole(client, group, policyName, verbose)
+			err = webapi.VaultRemoveRole(client, roleName(group), verbose)
 			if err != nil {
-				log.Fatalf("error create role: %v", err)
+				log.Fatalf("error deleting role: %v", err)
 			}
 
-			// enable KV
+			// disable KV
 			path := fmt.Sprintf("%s%s", kvprefix, group)
-			err = webapi.VaultEnableKVv2(client, path, verbose)
+			err = webapi.VaultDisableKVv2(client, path, verbose)
+			if err != nil {
+				log.Fatalf("error disable kv, got: %v ", err)
+			}
+
+			log.Printf("policy and kv-vault successfully removed from HCV.\n")
+		}
+	case "formula":
+		{
+			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
 			if err != nil {
-				log.Fatalf("error enabling kv, got: %v ", err)
+				log.Fatalf("error during SUMA login. Errorcode %v", err)
 			}
 
-			// write AppRole Output to KV
-			path = fmt.Sprintf("%s%s/data/approle_output", kvprefix, group)
-			err = webapi.VaultUpdateSecret(client, path, "role_id", grouproleID, verbose)
+			if !isEmpty(formulafile) {
+				formulas, err := readFormulaSpec(formulafile)
+				if err != nil {
+					log.Fatalf("error reading formulas: %v", err)
+				}
+				err = webapi.SumaSetGroupFormulas(sessioncookie, sumaurl, group, formulas, verbose)
+				if err != nil {
+					log.Fatalf("error assigning formulas: %v", err)
+				}
+			}
+
+			formulas, err := webapi.SumaGetGroupFormulas(sessioncookie, sumaurl, group, verbose)
 			if err != nil {
-				log.Fatalf("error writing secret to vault: %v", err)
+				log.Fatalf("error getting formulas: %v", err)
+			}
+			if err := writeFormulas(os.Stdout, group, formulas); err != nil {
+				log.Fatalf("error writing formulas: %v", err)
+			}
+		}
+	case "rotate":
+		{
+			exists, err := webapi.VaultRoleExists(client, roleName(group), verbose)
+			if err != nil {
+				log.Fatalf("error reading role: %v", err)
+			}
+			if !exists {
+				log.Fatalf("role %s not found.", group)
+			}
+
+			// list the old secret IDs before the new one is created
+			var oldaccessors []string
+			if destroyold {
+				oldaccessors, err = webapi.VaultListSecretIDAccessors(client, roleName(group), verbose)
+				if err != nil {
+					log.Fatalf("error listing secret IDs: %v", err)
+				}
 			}
 
-			err = webapi.VaultUpdateSecret(client, path, "secret_id", groupsecretID, verbose)
+			newsecretID, accessor, wrapToken, err := webapi.VaultCreateSecretID(client, roleName(group), wrapttl, verbose)
 			if err != nil {
-				log.Fatalf("error writing secret to vault: %v", err)
+				log.Fatalf("error creating secret ID: %v", err)
 			}
 
-			// write Network to KV
-			path = fmt.Sprintf("%s%s/data/config", kvprefix, group)
-			err = webapi.VaultUpdateSecret(client, path, "network", network, verbose)
+			// a wrapped secret ID is not known and must not be left behind in the KV store
+			path := fmt.Sprintf("%s%s/data/approle_output", kvprefix, group)
+			if wrapToken == "" {
+				err = webapi.VaultUpdateSecret(client, path, "secret_id", newsecretID, verbose)
+			} else {
+				err = webapi.VaultRemoveSecretKey(client, path, "secret_id", verbose)
+			}
+			if err != nil {
+				log.Fatalf("error writing secret to vault: %v", err)
+			}
+			// an unknown accessor must not leave the accessor of the old secret ID behind
+			if accessor != "" {
+				err = webapi.VaultUpdateSecret(client, path, "secret_id_accessor", accessor, verbose)
+			} else {
+				err = webapi.VaultRemoveSecretKey(client, path, "secret_id_accessor", verbose)
+			}
 			if err != nil {
 				log.Fatalf("error writing secret to vault: %v", err)
 			}
 
-			fmt.Fprintf(os.Stdout, "API Login-Information for User: %s\nroleID=%s\nsecretID=%s\n", group, grouproleID, groupsecretID)
+			if err := writeSecretID(os.Stdout, group, newsecretID, wrapToken, wrapttl); err != nil {
+				log.Fatalf("error writing secret ID: %v", err)
+			}
 
+			destroyed, failed := 0, 0
+			for _, a := range oldaccessors {
+				if a == accessor {
+					continue
+				}
+				if err := webapi.VaultDestroySecretIDAccessor(client, roleName(group), a, verbose); err != nil {
+					log.Printf("an error occured, got error %v", err)
+					failed++
+					continue
+				}
+				destroyed++
+			}
+			if destroyold {
+				log.Printf("%d of %d old secret IDs of role %s destroyed.\n", destroyed, len(oldaccessors), group)
+			}
+			if failed > 0 {
+				log.Fatalf("%d old secret IDs of role %s could not be destroyed.", failed, group)
+			}
 		}
-	case "delete":
+	case "update":
+		{
+			exists, err := webapi.VaultRoleExists(client, roleName(group), verbose)
+			if err != nil {
+				log.Fatalf("error reading role: %v", err)
+			}
+			if !exists {
+				log.Fatalf("role %s not found.", group)
+			}
+
+			options, err := roleOptions(network, true)
+			if err != nil {
+				log.Fatalf("error in role options: %v", err)
+			}
+
+			err = webapi.VaultUpdateRole(client, roleName(group), roleName(group)+policySuffix, options, verbose)
+			if err != nil {
+				log.Fatalf("error updating role: %v", err)
+			}
+
+			log.Printf("Role %s updated.\n", group)
+		}
+	case "list":
 		{
 			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
 			if err != nil {
 				log.Fatalf("error during SUMA login. Errorcode %v", err)
 			}
-			if verbose {
-				log.Printf("DEBUG MAIN: Session Cookie for SUMA: %s\n", sessioncookie)
-			}
 
-			err = webapi.SumaRemoveUser(sessioncookie, group, sumaurl, verbose)
+			// the groups are the same as in the audit, a group without KV store is listed with its status
+			inv, err := vaultInventory(client)
 			if err != nil {
-				log.Printf("an error occured, got error %v", err)
+				log.Fatal(err)
+			}
+			users, err := webapi.SumaListUserStates(sessioncookie, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error listing users: %v", err)
+			}
+			counts, err := webapi.SumaListSystemGroupCounts(sessioncookie, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error listing systemgroups: %v", err)
+			}
+			for u := range users {
+				inv.Users = append(inv.Users, u)
+			}
+			for sg := range counts {
+				inv.SystemGroups = append(inv.SystemGroups, sg)
+			}
+			exclude, err := adminExclude(client, sumalogin)
+			if err != nil {
+				log.Fatalf("error reading the role of the token: %v", err)
+			}
+
+			var tenants []tenantInfoType
+			for _, t := range correlateTenants(inv, exclude) {
+				if !isEmpty(group) && t.Group != group {
+					continue
+				}
+				var config map[string]interface{}
+				if t.Mount {
+					config, err = webapi.VaultGetSecrets(client, vaultAddress, kvprefix+t.Group, "config", verbose)
+					if err != nil {
+						log.Printf("no config found for group %s.\n", t.Group)
+					}
+				}
+				var role webapi.RoleOptionsType
+				found := false
+				if t.Role {
+					role, found, err = webapi.VaultReadRole(client, roleName(t.Group), verbose)
+					if err != nil {
+						log.Printf("could not read role of group %s: %v\n", t.Group, err)
+					}
+				}
+				info := tenantInfo(t.Group, config, role, found, users, counts)
+				info.Status = t.Status()
+				tenants = append(tenants, info)
+			}
+
+			if jsonOutput {
+				err = writeTenantsJSON(os.Stdout, tenants)
 			} else {
-				log.Printf("user %s successfully removed from SUMA.\n", group)
+				err = writeTenants(os.Stdout, tenants)
+			}
+			if err != nil {
+				log.Fatalf("error writing groups: %v", err)
+			}
+		}
+	case "audit":
+		{
+			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error during SUMA login. Errorcode %v", err)
 			}
 
-			err = webapi.VaultDeletePolicy(client, group, verbose)
+			// never touch the AppRoles and policies of other applications and environments
+			inv, err := vaultInventory(client)
 			if err != nil {
-				log.Fatalf("error deleting policy: %v", err)
+				log.Fatal(err)
+			}
+			inv.Users, err = webapi.SumaListUsers(sessioncookie, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error listing users: %v", err)
+			}
+			inv.SystemGroups, err = webapi.SumaListSystemGroups(sessioncookie, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error listing systemgroups: %v", err)
 			}
 
-			err = webapi.VaultRemoveRole(client, group, verbose)
+			// never touch the admin role, the admin KV store and the SUMA admin
+			exclude, err := adminExclude(client, sumalogin)
 			if err != nil {
-				log.Fatalf("error deleting role: %v", err)
+				log.Fatalf("error reading the role of the token: %v", err)
 			}
 
-			// disable KV
-			path := fmt.Sprintf("%s%s", kvprefix, group)
-			err = webapi.VaultDisableKVv2(client, path, verbose)
+			all := correlateTenants(inv, exclude)
+			var tenants []tenantStateType
+			for _, t := range all {
+				if isEmpty(group) || t.Group == group {
+					tenants = append(tenants, t)
+				}
+			}
+
+			if err := writeAudit(os.Stdout, tenants); err != nil {
+				log.Fatalf("error writing audit: %v", err)
+			}
+			if isEmpty(group) {
+				users, systemgroups := unmatchedPieces(inv, all, exclude)
+				if err := writeUnmatched(os.Stdout, users, systemgroups); err != nil {
+					log.Fatalf("error writing audit: %v", err)
+				}
+			}
+
+			var broken []tenantStateType
+			for _, t := range tenants {
+				if t.Status() != "ok" {
+					broken = append(broken, t)
+				}
+			}
+			if len(broken) == 0 || !fix {
+				if len(broken) > 0 {
+					os.Exit(1)
+				}
+				break
+			}
+
+			policyTemplate, err := webapi.ReadPolicyTemplate(policytemplate)
 			if err != nil {
-				log.Fatalf("error disable kv, got: %v ", err)
+				log.Fatalf("error reading policy template: %v", err)
 			}
 
-			log.Printf("policy and kv-vault successfully removed from HCV.\n")
+			if !yes && !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Fix %d groups?", len(broken))) {
+				os.Exit(1)
+			}
+
+			failed := 0
+			for _, t := range broken {
+				if err := fixTenant(client, sessioncookie, sumaurl, policyTemplate, t); err != nil {
+					log.Printf("could not fix group %s: %v\n", t.Group, err)
+					failed++
+					continue
+				}
+				fmt.Printf("fixed group %s (%s)\n", t.Group, t.Status())
+			}
+			if failed > 0 {
+				os.Exit(1)
+			}
+		}
+	case "checkpolicy":
+		{
+			policyTemplate, err := webapi.ReadPolicyTemplate(policytemplate)
+			if err != nil {
+				log.Fatalf("error reading policy template: %v", err)
+			}
+
+			groups := []string{group}
+			if isEmpty(group) {
+				groups, err = webapi.VaultListTenants(client, kvprefix, adminmount, verbose)
+				if err != nil {
+					log.Fatalf("error listing groups: %v", err)
+				}
+			}
+
+			uptodate := true
+			for _, g := range groups {
+				config, err := webapi.VaultGetSecrets(client, vaultAddress, kvprefix+g, "config", verbose)
+				if err != nil || config["network"] == nil || config["network"] == "" {
+					log.Printf("skip group %s, network not definied.\n", g)
+					uptodate = false
+					continue
+				}
+				policy, err := groupPolicy(policyTemplate, g, fmt.Sprintf("%s", config["network"]))
+				if err != nil {
+					log.Fatalf("error rendering policy: %v", err)
+				}
+				live, err := webapi.VaultReadPolicy(client, roleName(g)+policySuffix, verbose)
+				if err != nil {
+					log.Printf("skip group %s, got error %v\n", g, err)
+					uptodate = false
+					continue
+				}
+				if !checkPolicy(os.Stdout, g, policy, live) {
+					uptodate = false
+				}
+			}
+
+			if !uptodate {
+				os.Exit(1)
+			}
+		}
+	case "reap":
+		{
+			sessioncookie, err := webapi.SumaLogin(sumalogin, sumapassword, sumaurl, verbose)
+			if err != nil {
+				log.Fatalf("error during SUMA login. Errorcode %v", err)
+			}
+
+			groups := []string{group}
+			if isEmpty(group) {
+				groups, err = webapi.VaultListTenants(client, kvprefix, adminmount, verbose)
+				if err != nil {
+					log.Fatalf("error listing groups: %v", err)
+				}
+			}
+
+			var stale []webapi.StaleSystemType
+			networks := make(map[string]string)
+			for _, g := range groups {
+				config, err := webapi.VaultGetSecrets(client, vaultAddress, kvprefix+g, "config", verbose)
+				if err != nil || config["network"] == nil || config["network"] == "" {
+					log.Printf("skip group %s, network not definied.\n", g)
+					continue
+				}
+				networks[g] = fmt.Sprintf("%s", config["network"])
+				found, err := webapi.SumaFindStaleSystems(sessioncookie, sumaurl, g, networks[g], time.Now(), days, verbose)
+				if err != nil {
+					log.Printf("skip group %s, got error %v\n", g, err)
+					continue
+				}
+				stale = append(stale, found...)
+			}
+
+			if len(stale) == 0 {
+				fmt.Println("No stale systems found.")
+			} else {
+				writeStaleSystems(os.Stdout, stale)
+			}
+
+			action := "removed"
+			if deletesystems {
+				action = "deleted"
+			}
+
+			var results []reapResultType
+			reap := len(stale) > 0 && (yes || confirm(os.Stdin, os.Stdout, fmt.Sprintf("Reap %d systems?", len(stale))))
+			for _, s := range stale {
+				result := reapResultType{StaleSystemType: s, Action: "skipped"}
+				if reap {
+					if deletesystems {
+						_, _, err = webapi.SumaDeleteSystem(sessioncookie, sumaurl, s.Name, networks[s.Group], webapi.CleanupFailOnError, verbose)
+					} else {
+						_, err = webapi.SumaRemoveSystem(sessioncookie, sumaurl, s.Name, s.Group, networks[s.Group], verbose)
+					}
+					if err != nil {
+						result.Action = "failed"
+						result.Error = err.Error()
+						log.Printf("could not reap %s: %v\n", s.Name, err)
+					} else {
+						result.Action = action
+						fmt.Printf("%s %s from group %s\n", action, s.Name, s.Group)
+					}
+				}
+				results = append(results, result)
+			}
+
+			if !isEmpty(reportfile) {
+				if err := writeReapReport(reportfile, results); err != nil {
+					log.Fatalf("error writing report: %v", err)
+				}
+			}
 		}
 	}
 	os.Exit(0)
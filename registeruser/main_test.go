@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"os"
+	"path/filepath"
+	"registersystem/webapi"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsURL(t *testing.T) {
@@ -54,6 +60,15 @@ func TestGetTask(t *testing.T) {
 		{"a", "add"},
 		{"delete", "delete"},
 		{"d", "delete"},
+		{"formula", "formula"},
+		{"f", "formula"},
+		{"list", "list"},
+		{"l", "list"},
+		{"reap", "reap"},
+		{"audit", "audit"},
+		{"checkpolicy", "checkpolicy"},
+		{"rotate", "rotate"},
+		{"update", "update"},
 		{"firefox", "error"},
 	}
 
@@ -89,6 +104,84 @@ func TestCheckFlag(t *testing.T) {
 			t.Errorf("Expected checkFlag to fail for invalid input set #%d: %+v", i, inv)
 		}
 	}
+
+	// password and network are only required to add or delete a group
+	if checkFlag("role", "secret", "group", "", "127.0.0.0", "http://vault", "delete") {
+		t.Error("Expected delete task to fail checkFlag without password")
+	}
+	if checkFlag("role", "secret", "group", "grouppassword", "", "http://vault", "delete") {
+		t.Error("Expected delete task to fail checkFlag without network")
+	}
+	if !checkFlag("role", "secret", "group", "", "", "http://vault", "formula") {
+		t.Error("Expected formula task to pass checkFlag without password and network")
+	}
+
+	// reap scans all groups without a group
+	if !checkFlag("role", "secret", "", "", "", "http://vault", "reap") {
+		t.Error("Expected reap task to pass checkFlag without group")
+	}
+	if !checkFlag("role", "secret", "", "", "", "http://vault", "list") {
+		t.Error("Expected list task to pass checkFlag without group")
+	}
+	if !checkFlag("role", "secret", "", "", "", "http://vault", "audit") {
+		t.Error("Expected audit task to pass checkFlag without group")
+	}
+	if !checkFlag("role", "secret", "", "", "", "http://vault", "checkpolicy") {
+		t.Error("Expected checkpolicy task to pass checkFlag without group")
+	}
+	if checkFlag("role", "secret", "", "", "", "http://vault", "formula") {
+		t.Error("Expected formula task to fail checkFlag without group")
+	}
+}
+
+func TestReadFormulaSpec(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`{"formulas": [{"name": "locale", "data": {"timezone": "Europe/Berlin"}}, {"name": "prometheus-exporters"}]}`), 0600)
+
+	formulas, err := readFormulaSpec(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(formulas) != 2 || formulas[0].Name != "locale" || formulas[0].Data["timezone"] != "Europe/Berlin" {
+		t.Errorf("unexpected formulas: %+v", formulas)
+	}
+
+	invalids := map[string]string{
+		"noname.json":    `{"formulas": [{"data": {}}]}`,
+		"duplicate.json": `{"formulas": [{"name": "locale"}, {"name": "locale"}]}`,
+		"broken.json":    `{"formulas": [`,
+	}
+	for name, content := range invalids {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(content), 0600)
+		if _, err := readFormulaSpec(file); err == nil {
+			t.Errorf("Expected readFormulaSpec to fail for %s", name)
+		}
+	}
+}
+
+func TestWriteFormulas(t *testing.T) {
+	var buf bytes.Buffer
+
+	formulas := []webapi.FormulaType{
+		{Name: "locale", Data: map[string]interface{}{"timezone": "Europe/Berlin"}},
+		{Name: "prometheus-exporters"},
+	}
+	if err := writeFormulas(&buf, "group", formulas); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Formula: locale") || !strings.Contains(out, `"timezone": "Europe/Berlin"`) || !strings.Contains(out, "Formula: prometheus-exporters") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	buf.Reset()
+	writeFormulas(&buf, "group", nil)
+	if !strings.Contains(buf.String(), "No formulas") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
 }
 
 func TestFlagParsing(t *testing.T) {
@@ -139,4 +232,224 @@ func TestFlagParsing(t *testing.T) {
 	if task != "add" {
 		t.Errorf("Expected task to be 'add', got %q", task)
 	}
+	if kvprefix != "kv-clab-" || adminmount != "kv-clab-dagobah" || adminpath != "suma" {
+		t.Errorf("Expected the default KV locations, got %q %q %q", kvprefix, adminmount, adminpath)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got := confirm(strings.NewReader(tt.answer), &out, "Reap 2 systems?")
+		if got != tt.want {
+			t.Errorf("confirm(%q) = %v; want %v", tt.answer, got, tt.want)
+		}
+		if out.String() != "Reap 2 systems? [y/N] " {
+			t.Errorf("unexpected question: %q", out.String())
+		}
+	}
+}
+
+func TestWriteReapReport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.json")
+
+	results := []reapResultType{
+		{StaleSystemType: webapi.StaleSystemType{Group: "group", Name: "a.example.com", Reason: "expired at 2026-10-01"}, Action: "removed"},
+		{StaleSystemType: webapi.StaleSystemType{Group: "group", Name: "b.example.com"}, Action: "failed", Error: "boom"},
+	}
+	if err := writeReapReport(file, results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("could not read report: %v", err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if len(got) != 2 || got[0]["name"] != "a.example.com" || got[0]["action"] != "removed" || got[1]["error"] != "boom" {
+		t.Errorf("unexpected report: %s", content)
+	}
+}
+
+func TestWriteSecretID(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeSecretID(&buf, "group", "new-secret", "", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "New secretID for User: group\nsecretID=new-secret\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeSecretID(&buf, "group", "", "hvs.wrapped", 15*time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "wrappingToken=hvs.wrapped") || !strings.Contains(buf.String(), "15m0s") || strings.Contains(buf.String(), "secretID=") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestRoleOptions(t *testing.T) {
+	origTTL, origUses, origSecretIDCIDRs, origTokenCIDRs := secretidttl, secretiduses, secretidcidrs, tokencidrs
+	defer func() {
+		secretidttl, secretiduses, secretidcidrs, tokencidrs = origTTL, origUses, origSecretIDCIDRs, origTokenCIDRs
+	}()
+
+	secretidttl, secretiduses, secretidcidrs, tokencidrs = 24*time.Hour, 5, "", "none"
+	options, err := roleOptions("172.1.22.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options.SecretIDTTL != 24*time.Hour || options.SecretIDNumUses != 5 {
+		t.Errorf("unexpected secret ID constraints: %+v", options)
+	}
+	if len(options.SecretIDBoundCIDRs) != 1 || options.SecretIDBoundCIDRs[0] != "172.1.22.0/24" {
+		t.Errorf("expected secret IDs bound to the network, got %v", options.SecretIDBoundCIDRs)
+	}
+	if len(options.TokenBoundCIDRs) != 0 {
+		t.Errorf("expected unbound tokens, got %v", options.TokenBoundCIDRs)
+	}
+
+	tokencidrs = "172.1.22.0/24, 10.0.0.0/8"
+	options, err = roleOptions("172.1.22.0", false)
+	if err != nil || len(options.TokenBoundCIDRs) != 2 || options.TokenBoundCIDRs[1] != "10.0.0.0/8" {
+		t.Errorf("unexpected token CIDRs: %v %v", options.TokenBoundCIDRs, err)
+	}
+
+	secretidcidrs = "172.1.22.0"
+	if _, err := roleOptions("172.1.22.0", false); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
+
+func TestRoleOptions_Update(t *testing.T) {
+	origTokenTTL, origTokenMaxTTL, origTokenType, origPeriod, origPolicies := tokenttl, tokenmaxttl, tokentype, period, policies
+	origSecretIDCIDRs, origTokenCIDRs := secretidcidrs, tokencidrs
+	defer func() {
+		tokenttl, tokenmaxttl, tokentype, period, policies = origTokenTTL, origTokenMaxTTL, origTokenType, origPeriod, origPolicies
+		secretidcidrs, tokencidrs = origSecretIDCIDRs, origTokenCIDRs
+	}()
+
+	tokenttl, tokenmaxttl, tokentype, period, policies = 30*time.Minute, 8*time.Hour, "Batch", 0, "monitoring, audit"
+	secretidcidrs, tokencidrs = "", "none"
+	options, err := roleOptions("", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options.TokenTTL != 30*time.Minute || options.TokenMaxTTL != 8*time.Hour || options.TokenType != "batch" {
+		t.Errorf("unexpected token options: %+v", options)
+	}
+	if len(options.Policies) != 2 || options.Policies[1] != "audit" {
+		t.Errorf("unexpected policies: %v", options.Policies)
+	}
+	if options.SecretIDBoundCIDRs != nil {
+		t.Errorf("expected unchanged secret ID CIDRs, got %v", options.SecretIDBoundCIDRs)
+	}
+	if options.TokenBoundCIDRs == nil || len(options.TokenBoundCIDRs) != 0 {
+		t.Errorf("expected token CIDRs to be removed, got %v", options.TokenBoundCIDRs)
+	}
+
+	policies = "none"
+	if options, _ := roleOptions("", true); options.Policies == nil || len(options.Policies) != 0 {
+		t.Errorf("expected policies to be removed, got %v", options.Policies)
+	}
+
+	tokentype = "periodic"
+	if _, err := roleOptions("", true); err == nil {
+		t.Error("expected error for invalid token type")
+	}
+
+	tokentype, tokenttl = "", 12*time.Hour
+	if _, err := roleOptions("", true); err == nil {
+		t.Error("expected error for tokenttl exceeding tokenmaxttl")
+	}
+}
+
+func TestWriteLoginInformation(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeLoginInformation(&buf, "group", "role", "secret", "", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "API Login-Information for User: group\nroleID=role\nsecretID=secret\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeLoginInformation(&buf, "group", "role", "", "hvs.wrapped", 15*time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "roleID=role\nwrappingToken=hvs.wrapped\n") || strings.Contains(buf.String(), "secretID=") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	origKVPrefix, origAdminMount, origAdminPath, origEnv := kvprefix, adminmount, adminpath, env
+	defer func() { kvprefix, adminmount, adminpath, env = origKVPrefix, origAdminMount, origAdminPath, origEnv }()
+
+	kvprefix, adminmount, adminpath, env = "kv-lab2-", "kv-lab2-admin", "suma2", "lab2"
+	rendered, err := groupPolicy(webapi.DefaultPolicyTemplate, "alpha", "172.1.22.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(rendered, `path "kv-lab2-alpha*"`) || !strings.Contains(rendered, `path "kv-lab2-admin/data/suma2"`) ||
+		!strings.Contains(rendered, `path "sys/policies/acl/lab2-alpha_read_policy"`) {
+		t.Errorf("unexpected policy: %s", rendered)
+	}
+
+	var buf bytes.Buffer
+	if !checkPolicy(&buf, "alpha", rendered, rendered) || !strings.Contains(buf.String(), "up to date") {
+		t.Errorf("expected matching policy, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if checkPolicy(&buf, "alpha", rendered, "") || !strings.Contains(buf.String(), "missing") {
+		t.Errorf("expected missing policy, got %q", buf.String())
+	}
+
+	buf.Reset()
+	live := strings.Replace(rendered, "lookup-self", "lookup", 1)
+	if checkPolicy(&buf, "alpha", rendered, live) || !strings.Contains(buf.String(), `- path "auth/token/lookup-self" {`) {
+		t.Errorf("expected differing policy, got %q", buf.String())
+	}
+}
+
+func TestRoleName(t *testing.T) {
+	origEnv := env
+	defer func() { env = origEnv }()
+
+	env = ""
+	if got := roleName("alpha"); got != "alpha" {
+		t.Errorf("expected the group as role name without environment, got %q", got)
+	}
+	if g, ok := groupOfRole("lab2-alpha"); !ok || g != "lab2-alpha" {
+		t.Errorf("unexpected group %q %v", g, ok)
+	}
+
+	env = "lab2"
+	if got := roleName("alpha"); got != "lab2-alpha" {
+		t.Errorf("expected the environment prefixed role name, got %q", got)
+	}
+	for name, want := range map[string]string{"lab2-alpha": "alpha", "alpha": "", "lab2-": "", "lab3-alpha": ""} {
+		g, ok := groupOfRole(name)
+		if g != want || ok != (want != "") {
+			t.Errorf("%s: got group %q %v; want %q", name, g, ok, want)
+		}
+	}
 }
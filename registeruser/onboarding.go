@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"registersystem/webapi"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// stepType is a step of the onboarding of a group. undo compensates a successful or a partially done do, a nil undo
+// has nothing to compensate.
+type stepType struct {
+	name string
+	do   func() error
+	undo func() error
+}
+
+// rollbackType reports the steps, which were undone after a failed onboarding.
+type rollbackType struct {
+	RolledBack []string
+	Failed     []string
+}
+
+func (r rollbackType) String() string {
+	line := fmt.Sprintf("rolled back: [%s]", strings.Join(r.RolledBack, ", "))
+	if len(r.Failed) > 0 {
+		line += fmt.Sprintf(", rollback failed: [%s]", strings.Join(r.Failed, ", "))
+	}
+	return line
+}
+
+// runSteps runs the steps in order. If a step fails, the failed step and the completed steps are undone in reverse
+// order, so the failed step compensates its own partial work.
+func runSteps(steps []stepType, verbose bool) (rollback rollbackType, err error) {
+
+	for i, step := range steps {
+		if verbose {
+			log.Printf("DEBUG MAIN runSteps: %s\n", step.name)
+		}
+
+		err = step.do()
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("%s failed: %v", step.name, err)
+		for j := i; j >= 0; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			if uerr := steps[j].undo(); uerr != nil {
+				log.Printf("could not roll back %s: %v\n", steps[j].name, uerr)
+				rollback.Failed = append(rollback.Failed, steps[j].name)
+			} else {
+				rollback.RolledBack = append(rollback.RolledBack, steps[j].name)
+			}
+		}
+		return rollback, err
+	}
+
+	return rollback, nil
+}
+
+// onboardingSteps returns the steps to add the group. Resources, which already exist before the onboarding or whose
+// existence could not be checked, are not removed by the rollback. The secrets are removed together with a new KV
+// store, the overwritten secrets of an existing KV store are restored.
+func onboardingSteps(client *api.Client, sessioncookie, sumaurl, policy string, options webapi.RoleOptionsType, activationkey *string) []stepType {
+
+	role := roleName(group)
+	policyName := role + policySuffix
+	path := fmt.Sprintf("%s%s", kvprefix, group)
+
+	userExisted, systemgroupExisted, keyExisted, policyExisted, roleExisted, mountExisted := true, true, true, true, true, true
+
+	// the secrets of an existing KV store before the onboarding by path
+	previous := make(map[string]map[string]interface{})
+
+	writeSecrets := func(secretpath string, secrets [][2]string) error {
+		if _, saved := previous[secretpath]; mountExisted && !saved {
+			data, err := webapi.VaultReadSecretData(client, secretpath, verbose)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				data = make(map[string]interface{})
+			}
+			previous[secretpath] = data
+		}
+		for _, s := range secrets {
+			if err := webapi.VaultUpdateSecret(client, secretpath, s[0], s[1], verbose); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	restoreSecrets := func(secretpath string, keys ...string) error {
+		data, saved := previous[secretpath]
+		if !saved {
+			return nil
+		}
+		for _, k := range keys {
+			var err error
+			if v, ok := data[k]; ok {
+				err = webapi.VaultUpdateSecret(client, secretpath, k, fmt.Sprintf("%v", v), verbose)
+			} else {
+				err = webapi.VaultRemoveSecretKey(client, secretpath, k, verbose)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	steps := []stepType{
+		{
+			name: "SUMA user",
+			do: func() error {
+				userExisted = webapi.SumaUserExists(sessioncookie, group, sumaurl, verbose)
+				// the password of an existing user is not changed, so a generated password would not be its password
+				if userExisted && genpass {
+					return fmt.Errorf("user %s already exists, its password cannot be generated", group)
+				}
+				result, err := webapi.SumaAddUser(sessioncookie, group, grouppassword, sumaurl, verbose)
+				if err == nil && result != http.StatusOK {
+					err = fmt.Errorf("got http error %d", result)
+				}
+				return err
+			},
+			// the SystemGroup is undone by the activation key
+			undo: func() error {
+				if userExisted {
+					return nil
+				}
+				return webapi.SumaDeleteUser(sessioncookie, group, sumaurl, verbose)
+			},
+		},
+		{
+			name: "SUMA activation key",
+			do: func() (err error) {
+				systemgroupExisted = webapi.SumaSystemGroupExists(sessioncookie, sumaurl, group, verbose)
+				key, err := webapi.SumaFindActivationKey(sessioncookie, sumaurl, group, verbose)
+				if err != nil {
+					return err
+				}
+				keyExisted = key != ""
+				*activationkey, err = webapi.SumaCreateActivationKey(sessioncookie, sumaurl, group, basechannel, verbose)
+				return err
+			},
+			// the key may be created before adding the SystemGroup to it fails, so it is looked up again
+			undo: func() error {
+				if !keyExisted {
+					key, err := webapi.SumaFindActivationKey(sessioncookie, sumaurl, group, verbose)
+					if err != nil {
+						return err
+					}
+					if key != "" {
+						if err := webapi.SumaDeleteActivationKey(sessioncookie, sumaurl, key, verbose); err != nil {
+							return err
+						}
+					}
+				}
+				if systemgroupExisted || !webapi.SumaSystemGroupExists(sessioncookie, sumaurl, group, verbose) {
+					return nil
+				}
+				return webapi.SumaRemoveSystemGroup(sessioncookie, sumaurl, group, verbose)
+			},
+		},
+		{
+			name: "Vault policy",
+			do: func() (err error) {
+				existed, err := webapi.VaultPolicyExists(client, policyName, verbose)
+				if err != nil {
+					return err
+				}
+				policyExisted = existed
+				_, err = webapi.VaultCreatePolicy(client, role, policy, verbose)
+				return err
+			},
+			undo: func() error {
+				if policyExisted {
+					return nil
+				}
+				return webapi.VaultDeletePolicy(client, role, verbose)
+			},
+		},
+		{
+			name: "Vault AppRole",
+			do: func() (err error) {
+				existed, err := webapi.VaultRoleExists(client, role, verbose)
+				if err != nil {
+					return err
+				}
+				roleExisted = existed
+				grouproleID, groupsecretID, groupwraptoken, err = webapi.VaultCreateRole(client, role, policyName, options, wrapttl, verbose)
+				return err
+			},
+			undo: func() error {
+				if roleExisted {
+					return nil
+				}
+				return webapi.VaultRemoveRole(client, role, verbose)
+			},
+		},
+		{
+			name: "Vault KV store",
+			do: func() (err error) {
+				existed, err := webapi.VaultMountExists(client, path, verbose)
+				if err != nil {
+					return err
+				}
+				mountExisted = existed
+				return webapi.VaultEnableKVv2(client, path, verbose)
+			},
+			undo: func() error {
+				if mountExisted {
+					return nil
+				}
+				return webapi.VaultDisableKVv2(client, path, verbose)
+			},
+		},
+		{
+			name: "AppRole secrets",
+			do: func() error {
+				// a wrapped secret ID is only known to the receiver of the wrapping token
+				if groupwraptoken != "" {
+					if err := writeSecrets(path+"/data/approle_output", [][2]string{{"role_id", grouproleID}}); err != nil {
+						return err
+					}
+					return webapi.VaultRemoveSecretKey(client, path+"/data/approle_output", "secret_id", verbose)
+				}
+				return writeSecrets(path+"/data/approle_output", [][2]string{
+					{"role_id", grouproleID},
+					{"secret_id", groupsecretID},
+				})
+			},
+			undo: func() error {
+				return restoreSecrets(path+"/data/approle_output", "role_id", "secret_id")
+			},
+		},
+		{
+			name: "group config",
+			do: func() error {
+				return writeSecrets(path+"/data/config", [][2]string{
+					{"network", network},
+					{"activationkey", *activationkey},
+					{"base_channel", basechannel},
+					{"child_channels", childchannels},
+					{"config_channels", configchannels},
+					{createdAtKey, time.Now().UTC().Format(time.RFC3339)},
+				})
+			},
+			undo: func() error {
+				return restoreSecrets(path+"/data/config", "network", "activationkey", "base_channel", "child_channels", "config_channels", createdAtKey)
+			},
+		},
+	}
+
+	// a generated password is only known to the KV store of the group
+	if genpass {
+		steps = append(steps, stepType{
+			name: "SUMA password",
+			do: func() error {
+				return writeSecrets(path+"/data/suma", [][2]string{
+					{"login", group},
+					{"password", grouppassword},
+				})
+			},
+			undo: func() error {
+				return restoreSecrets(path+"/data/suma", "login", "password")
+			},
+		})
+	}
+
+	return steps
+}
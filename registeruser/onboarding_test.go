@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"registersystem/webapi"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func recordStep(name string, calls *[]string, doErr, undoErr error) stepType {
+	return stepType{
+		name: name,
+		do: func() error {
+			*calls = append(*calls, "do "+name)
+			return doErr
+		},
+		undo: func() error {
+			*calls = append(*calls, "undo "+name)
+			return undoErr
+		},
+	}
+}
+
+func TestRunSteps_Success(t *testing.T) {
+	var calls []string
+	steps := []stepType{
+		recordStep("user", &calls, nil, nil),
+		recordStep("policy", &calls, nil, nil),
+	}
+
+	rollback, err := runSteps(steps, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rollback.RolledBack) != 0 || strings.Join(calls, ",") != "do user,do policy" {
+		t.Errorf("unexpected calls %v, rollback %+v", calls, rollback)
+	}
+}
+
+func TestRunSteps_Rollback(t *testing.T) {
+	var calls []string
+	steps := []stepType{
+		recordStep("user", &calls, nil, nil),
+		{name: "secrets", do: func() error { calls = append(calls, "do secrets"); return nil }},
+		recordStep("policy", &calls, nil, nil),
+		recordStep("role", &calls, errors.New("permission denied"), nil),
+		recordStep("kv", &calls, nil, nil),
+	}
+
+	rollback, err := runSteps(steps, false)
+	if err == nil || err.Error() != "role failed: permission denied" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if strings.Join(calls, ",") != "do user,do secrets,do policy,do role,undo role,undo policy,undo user" {
+		t.Errorf("expected the failed and the completed steps to be undone in reverse order, got %v", calls)
+	}
+	if rollback.String() != "rolled back: [role, policy, user]" {
+		t.Errorf("unexpected rollback report: %s", rollback)
+	}
+}
+
+func TestRunSteps_RollbackFails(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var calls []string
+	steps := []stepType{
+		recordStep("user", &calls, nil, nil),
+		recordStep("policy", &calls, nil, errors.New("policy locked")),
+		recordStep("role", &calls, errors.New("permission denied"), nil),
+	}
+
+	rollback, err := runSteps(steps, false)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if strings.Join(calls, ",") != "do user,do policy,do role,undo role,undo policy,undo user" {
+		t.Errorf("expected rollback to continue after a failed undo, got %v", calls)
+	}
+	if rollback.String() != "rolled back: [role, user], rollback failed: [policy]" {
+		t.Errorf("unexpected rollback report: %s", rollback)
+	}
+}
+
+func TestOnboardingSteps_GenpassExistingUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/user/listUsers":
+			fmt.Fprint(w, `{"success": true, "result": [{"login": "tenant1"}]}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	origGroup, origGenpass := group, genpass
+	defer func() { group, genpass = origGroup, origGenpass }()
+	group, genpass = "tenant1", true
+
+	var activationkey string
+	steps := onboardingSteps(nil, "cookie", server.URL, "", webapi.RoleOptionsType{}, &activationkey)
+
+	err := steps[0].do()
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected existing user error, got %v", err)
+	}
+	if err := steps[0].undo(); err != nil {
+		t.Errorf("the existing user must not be removed, got %v", err)
+	}
+}
+
+// newKVServer serves an existing KV store kv-clab-alpha with the secrets by name.
+func newKVServer(t *testing.T, secrets map[string]map[string]interface{}) *api.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/mounts" {
+			fmt.Fprint(w, `{"data": {"kv-clab-alpha/": {"type": "kv", "options": {"version": "2"}}}}`)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/v1/kv-clab-alpha/data/")
+		if name == r.URL.Path {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := secrets[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors": []}`)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		default:
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			secrets[name] = body.Data
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("could not create Vault client: %v", err)
+	}
+	client.SetToken("token")
+	return client
+}
+
+func TestRunSteps_RollbackExistingMount(t *testing.T) {
+	origGroup, origGenpass, origPassword, origKVPrefix, origEnv := group, genpass, grouppassword, kvprefix, env
+	origRoleID, origSecretID, origWrapToken, origNetwork := grouproleID, groupsecretID, groupwraptoken, network
+	defer func() {
+		group, genpass, grouppassword, kvprefix, env = origGroup, origGenpass, origPassword, origKVPrefix, origEnv
+		grouproleID, groupsecretID, groupwraptoken, network = origRoleID, origSecretID, origWrapToken, origNetwork
+	}()
+	group, genpass, grouppassword, kvprefix, env = "alpha", true, "new-password", webapi.DefaultKVPrefix, ""
+	grouproleID, groupsecretID, groupwraptoken, network = "new-role", "new-secret", "", "192.168.1.0"
+
+	secrets := map[string]map[string]interface{}{
+		"approle_output": {"role_id": "old-role", "secret_id": "old-secret"},
+		"config":         {"network": "192.168.1.0", "activationkey": "1-alpha"},
+		"suma":           {"login": "alpha", "password": "old-password"},
+	}
+	client := newKVServer(t, secrets)
+
+	var activationkey string
+	var steps []stepType
+	for _, step := range onboardingSteps(client, "cookie", "", "", webapi.RoleOptionsType{}, &activationkey) {
+		switch step.name {
+		case "Vault KV store", "AppRole secrets", "group config", "SUMA password":
+			steps = append(steps, step)
+		}
+	}
+	steps = append(steps, stepType{name: "failing", do: func() error { return errors.New("permission denied") }})
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	rollback, err := runSteps(steps, false)
+	if err == nil || len(rollback.Failed) != 0 {
+		t.Fatalf("expected a clean rollback, got %v %s", err, rollback)
+	}
+
+	if secrets["approle_output"]["role_id"] != "old-role" || secrets["approle_output"]["secret_id"] != "old-secret" {
+		t.Errorf("expected the previous AppRole secrets, got %v", secrets["approle_output"])
+	}
+	if _, ok := secrets["config"][createdAtKey]; ok || secrets["config"]["activationkey"] != "1-alpha" {
+		t.Errorf("expected the previous config, got %v", secrets["config"])
+	}
+	if secrets["suma"]["password"] != "old-password" {
+		t.Errorf("expected the previous SUMA password, got %v", secrets["suma"])
+	}
+}
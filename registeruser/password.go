@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// passwordLength is the length of generated passwords.
+const passwordLength = 24
+
+const (
+	lowerChars   = "abcdefghijkmnopqrstuvwxyz"
+	upperChars   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	digitChars   = "23456789"
+	specialChars = "!#%+-.=_"
+)
+
+func randomChar(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
+
+// generatePassword returns a random password with at least one lower and upper case letter, digit and special
+// character, so that it meets the password policy of the SUSE Manager.
+func generatePassword(length int) (string, error) {
+
+	classes := []string{lowerChars, upperChars, digitChars, specialChars}
+	if length < len(classes) {
+		return "", fmt.Errorf("password length %d is too short", length)
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		chars := lowerChars + upperChars + digitChars + specialChars
+		if i < len(classes) {
+			chars = classes[i]
+		}
+		c, err := randomChar(chars)
+		if err != nil {
+			return "", fmt.Errorf("could not generate password: %v", err)
+		}
+		password[i] = c
+	}
+
+	// shuffle, so that the characters of the classes are not at the beginning
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("could not generate password: %v", err)
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for i := 0; i < 50; i++ {
+		password, err := generatePassword(passwordLength)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(password) != passwordLength {
+			t.Errorf("expected length %d, got %d", passwordLength, len(password))
+		}
+		for _, chars := range []string{lowerChars, upperChars, digitChars, specialChars} {
+			if !strings.ContainsAny(password, chars) {
+				t.Errorf("password %q contains none of %q", password, chars)
+			}
+		}
+		if seen[password] {
+			t.Errorf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+
+	if _, err := generatePassword(3); err == nil {
+		t.Error("expected error for too short password")
+	}
+}
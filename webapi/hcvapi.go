@@ -1,17 +1,29 @@
 package webapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 )
 
-// VaultGetSecrets reads the secrets
-func VaultGetSecrets(client *api.Client, vaultAddress, group, path string, verbose bool) (map[string]interface{}, error) {
+// Default locations of the KV stores, the KV store of a group is mounted at the prefix followed by the group. The
+// SUSE Manager credentials are stored at the admin path of the admin mount.
+const (
+	DefaultKVPrefix   = "kv-clab-"
+	DefaultAdminMount = "kv-clab-dagobah"
+	DefaultAdminPath  = "suma"
+)
+
+// VaultGetSecrets reads the secrets at the path of the KV version 2 mount.
+func VaultGetSecrets(client *api.Client, vaultAddress, mount, path string, verbose bool) (map[string]interface{}, error) {
 
 	// Path to the secret
-	secretPath := fmt.Sprintf("kv-clab-%s/data/%s", group, path)
+	secretPath := fmt.Sprintf("%s/data/%s", mount, path)
 	if verbose {
 		log.Printf("DEBUG HCVAPI VaultGetSecrets: secretPath = %s\n", secretPath)
 	}
@@ -102,22 +114,9 @@ func VaultLogout(client *api.Client, verbose bool) error {
 }
 
 // VaultCreatePolicy create the vault policy for the role.
-func VaultCreatePolicy(client *api.Client, group string, verbose bool) (policyName string, err error) {
+func VaultCreatePolicy(client *api.Client, group, policyContent string, verbose bool) (policyName string, err error) {
 
 	policyName = fmt.Sprintf("%s_read_policy", group)
-	policyContent := fmt.Sprintf(
-		`path "kv-clab-%s*" {
-		capabilities = ["list", "read"]
-	}
-path "kv-clab-dagobah/data/suma" {
-	capabilities = ["list", "read"]
-}
-path "sys/policies/acl/%s_read_policy" {
-	capabilities = ["read"]
-}
-path "auth/token/lookup-self" {
-  capabilities = ["read"]
-}`, group, group)
 
 	if verbose {
 		log.Printf("DEBUG HCVAPI VaultCreatePolicy: policyName: %s\n", policyName)
@@ -155,20 +154,92 @@ func VaultDeletePolicy(client *api.Client, group string, verbose bool) (err erro
 	return nil
 }
 
-// VaultCreateRole create a new role (user)
-func VaultCreateRole(client *api.Client, group, policyName string, verbose bool) (roleID, secretID string, err error) {
+// DefaultTokenTTL and DefaultTokenMaxTTL are the TTLs of the tokens of a new role.
+const (
+	DefaultTokenTTL    = time.Hour
+	DefaultTokenMaxTTL = 4 * time.Hour
+)
+
+// RoleOptionsType are the constraints of the secret IDs and tokens of a role. A zero TTL, period or number of uses
+// is unlimited on create and unchanged on update, a zero token TTL is the default on create. A nil list is unbound
+// on create and unchanged on update, an empty list removes the binding or the additional policies.
+type RoleOptionsType struct {
+	SecretIDTTL        time.Duration
+	SecretIDNumUses    int
+	SecretIDBoundCIDRs []string
+	TokenBoundCIDRs    []string
+	TokenTTL           time.Duration
+	TokenMaxTTL        time.Duration
+	TokenType          string
+	Period             time.Duration
+	Policies           []string
+}
+
+// NetworkCIDR returns the CIDR of the permitted network of a group.
+func NetworkCIDR(network string) string {
+	return network + "/24"
+}
+
+// roleData returns the parameters of the role. The policy is always attached to the role.
+func (o RoleOptionsType) roleData(policyName string, update bool) map[string]interface{} {
+
+	roleData := map[string]interface{}{}
+
+	if !update || o.Policies != nil {
+		roleData["policies"] = append([]string{policyName}, o.Policies...)
+	}
+
+	if !update {
+		if o.TokenTTL == 0 {
+			o.TokenTTL = DefaultTokenTTL
+		}
+		if o.TokenMaxTTL == 0 {
+			o.TokenMaxTTL = DefaultTokenMaxTTL
+		}
+	}
+
+	durations := map[string]time.Duration{
+		"token_ttl":     o.TokenTTL,
+		"token_max_ttl": o.TokenMaxTTL,
+		"token_period":  o.Period,
+		"secret_id_ttl": o.SecretIDTTL,
+	}
+	for key, d := range durations {
+		if d > 0 {
+			roleData[key] = int(d.Seconds())
+		}
+	}
+
+	if o.SecretIDNumUses > 0 {
+		roleData["secret_id_num_uses"] = o.SecretIDNumUses
+	}
+	if o.TokenType != "" {
+		roleData["token_type"] = o.TokenType
+	}
+	if o.SecretIDBoundCIDRs != nil && (update || len(o.SecretIDBoundCIDRs) > 0) {
+		roleData["secret_id_bound_cidrs"] = o.SecretIDBoundCIDRs
+	}
+	if o.TokenBoundCIDRs != nil && (update || len(o.TokenBoundCIDRs) > 0) {
+		roleData["token_bound_cidrs"] = o.TokenBoundCIDRs
+	}
+
+	return roleData
+}
+
+// VaultCreateRole create a new role (user). With a wrapTTL greater than 0 the secret ID is only returned as
+// response-wrapping token.
+func VaultCreateRole(client *api.Client, group, policyName string, options RoleOptionsType, wrapTTL time.Duration, verbose bool) (roleID, secretID, wrapToken string, err error) {
 
-	roleData := map[string]interface{}{
-		"policies":      []string{policyName},
-		"token_ttl":     3600,
-		"token_max_ttl": 14400,
+	roleData := options.roleData(policyName, false)
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultCreateRole: roleData = %v\n", roleData)
 	}
 
 	// Write the role to Vault
 	rolePath := fmt.Sprintf("auth/approle/role/%s", group)
 	_, err = client.Logical().Write(rolePath, roleData)
 	if err != nil {
-		return roleID, secretID, fmt.Errorf("failed to create role: %v", err)
+		return roleID, secretID, wrapToken, fmt.Errorf("failed to create role: %v", err)
 	}
 
 	if verbose {
@@ -180,13 +251,13 @@ func VaultCreateRole(client *api.Client, group, policyName string, verbose bool)
 	roleIDSecretResponse, err := client.Logical().Read(roleIDPath)
 
 	if err != nil {
-		return roleID, secretID, fmt.Errorf("failed to retrieve role ID: %v", err)
+		return roleID, secretID, wrapToken, fmt.Errorf("failed to retrieve role ID: %v", err)
 	}
 
 	roleID, ok := roleIDSecretResponse.Data["role_id"].(string)
 
 	if !ok {
-		return roleID, secretID, fmt.Errorf("failed to retrieve role ID: %v", err)
+		return roleID, secretID, wrapToken, fmt.Errorf("failed to retrieve role ID: %v", err)
 	}
 
 	if verbose {
@@ -194,24 +265,226 @@ func VaultCreateRole(client *api.Client, group, policyName string, verbose bool)
 	}
 
 	// get secretID
+	secretID, _, wrapToken, err = VaultCreateSecretID(client, group, wrapTTL, verbose)
+	if err != nil {
+		return roleID, secretID, wrapToken, err
+	}
+
+	if verbose {
+		log.Println("DEBUG HCVAPI VaultCreateRole: Got secretID: #########")
+	}
+
+	return roleID, secretID, wrapToken, nil
+}
+
+// roleInt returns the number in the data of a role.
+func roleInt(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// roleSeconds returns the duration of a number of seconds in the data of a role.
+func roleSeconds(v interface{}) time.Duration {
+	return time.Duration(roleInt(v)) * time.Second
+}
+
+// roleStrings returns the list of strings in the data of a role.
+func roleStrings(v interface{}) (list []string) {
+	items, _ := v.([]interface{})
+	for _, i := range items {
+		if s, ok := i.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// VaultReadRole returns the options of the role of the group. found is false, if the role does not exist. The
+// policies are all policies of the role including the read policy of the group.
+func VaultReadRole(client *api.Client, group string, verbose bool) (options RoleOptionsType, found bool, err error) {
+
+	secret, err := client.Logical().Read(fmt.Sprintf("auth/approle/role/%s", group))
+	if err != nil {
+		return options, false, fmt.Errorf("failed to read role %s: %v", group, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return options, false, nil
+	}
+
+	d := secret.Data
+	options.TokenTTL = roleSeconds(d["token_ttl"])
+	options.TokenMaxTTL = roleSeconds(d["token_max_ttl"])
+	options.Period = roleSeconds(d["token_period"])
+	options.SecretIDTTL = roleSeconds(d["secret_id_ttl"])
+	options.SecretIDNumUses = roleInt(d["secret_id_num_uses"])
+	options.TokenType, _ = d["token_type"].(string)
+	options.Policies = roleStrings(d["token_policies"])
+	if options.Policies == nil {
+		options.Policies = roleStrings(d["policies"])
+	}
+	options.SecretIDBoundCIDRs = roleStrings(d["secret_id_bound_cidrs"])
+	options.TokenBoundCIDRs = roleStrings(d["token_bound_cidrs"])
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultReadRole: role %s: %+v\n", group, options)
+	}
+
+	return options, true, nil
+}
+
+// VaultUpdateRole changes the options of an existing role, the policy stays attached to the role.
+func VaultUpdateRole(client *api.Client, group, policyName string, options RoleOptionsType, verbose bool) (err error) {
+
+	roleData := options.roleData(policyName, true)
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultUpdateRole: roleData = %v\n", roleData)
+	}
+
+	if len(roleData) == 0 {
+		return fmt.Errorf("no options to update")
+	}
+
+	_, err = client.Logical().Write(fmt.Sprintf("auth/approle/role/%s", group), roleData)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultUpdateRole: AppRole updated successfully: %s", group)
+	}
+
+	return nil
+}
+
+// VaultCreateSecretID generates a new secret ID for the role of the group. With a wrapTTL greater than 0 the secret
+// ID is only returned as response-wrapping token, which is valid for the wrapTTL. The accessor of a wrapped secret ID
+// is found by its listing and is empty, if it could not be told apart from secret IDs created at the same time.
+func VaultCreateSecretID(client *api.Client, group string, wrapTTL time.Duration, verbose bool) (secretID, accessor, wrapToken string, err error) {
+
 	secretIDPath := fmt.Sprintf("auth/approle/role/%s/secret-id", group)
+
+	if wrapTTL > 0 {
+		// wrap only this request, the client of the caller is not changed
+		wrapClient, err := client.Clone()
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to clone Vault client: %v", err)
+		}
+		wrapClient.SetToken(client.Token())
+		wrapClient.SetWrappingLookupFunc(func(operation, path string) string {
+			return wrapTTL.String()
+		})
+
+		// the wrapped accessor is the accessor of the wrapping token, not of the secret ID
+		before, err := VaultListSecretIDAccessors(client, group, verbose)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		secretIDResponse, err := wrapClient.Logical().Write(secretIDPath, map[string]interface{}{})
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to generate secret ID: %v", err)
+		}
+		if secretIDResponse == nil || secretIDResponse.WrapInfo == nil {
+			return "", "", "", fmt.Errorf("unexpected response format for wrapped secret ID")
+		}
+
+		after, err := VaultListSecretIDAccessors(client, group, verbose)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		known := make(map[string]bool)
+		for _, a := range before {
+			known[a] = true
+		}
+		var created []string
+		for _, a := range after {
+			if !known[a] {
+				created = append(created, a)
+			}
+		}
+		if len(created) == 1 {
+			accessor = created[0]
+		}
+
+		if verbose {
+			log.Printf("DEBUG HCVAPI VaultCreateSecretID: Got wrapped secretID with TTL %v\n", wrapTTL)
+		}
+
+		return "", accessor, secretIDResponse.WrapInfo.Token, nil
+	}
+
 	secretIDResponse, err := client.Logical().Write(secretIDPath, map[string]interface{}{})
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate secret ID: %v", err)
+	}
+	if secretIDResponse == nil {
+		return "", "", "", fmt.Errorf("unexpected response format for secret ID")
+	}
+
+	secretID, ok := secretIDResponse.Data["secret_id"].(string)
+	if !ok {
+		return "", "", "", fmt.Errorf("unexpected response format for secret ID")
+	}
+	accessor, _ = secretIDResponse.Data["secret_id_accessor"].(string)
+
+	if verbose {
+		log.Println("DEBUG HCVAPI VaultCreateSecretID: Got secretID: #########")
+	}
+
+	return secretID, accessor, "", nil
+}
 
+// VaultListSecretIDAccessors returns the accessors of all secret IDs of the role of the group.
+func VaultListSecretIDAccessors(client *api.Client, group string, verbose bool) (accessors []string, err error) {
+
+	secret, err := client.Logical().List(fmt.Sprintf("auth/approle/role/%s/secret-id", group))
 	if err != nil {
-		return roleID, secretID, fmt.Errorf("failed to generate secret ID: %v", err)
+		return nil, fmt.Errorf("failed to list secret ID accessors: %v", err)
 	}
 
-	secretID, ok = secretIDResponse.Data["secret_id"].(string)
+	// no secret IDs
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
 
-	if !ok {
-		return roleID, secretID, fmt.Errorf("unexpected response format for secret ID")
+	keys, _ := secret.Data["keys"].([]interface{})
+	for _, k := range keys {
+		if accessor, ok := k.(string); ok {
+			accessors = append(accessors, accessor)
+		}
 	}
 
 	if verbose {
-		log.Println("DEBUG HCVAPI VaultCreateRole: Got secretID: #########")
+		log.Printf("DEBUG HCVAPI VaultListSecretIDAccessors: found %d secret IDs of role %s\n", len(accessors), group)
 	}
 
-	return roleID, secretID, nil
+	return accessors, nil
+}
+
+// VaultDestroySecretIDAccessor destroys the secret ID of the accessor, so that it could not be used anymore.
+func VaultDestroySecretIDAccessor(client *api.Client, group, accessor string, verbose bool) (err error) {
+
+	_, err = client.Logical().Write(fmt.Sprintf("auth/approle/role/%s/secret-id-accessor/destroy", group), map[string]interface{}{
+		"secret_id_accessor": accessor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to destroy secret ID accessor %s: %v", accessor, err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultDestroySecretIDAccessor: destroyed secret ID accessor %s\n", accessor)
+	}
+
+	return nil
 }
 
 // VaultRemoveRole delete a role
@@ -306,6 +579,215 @@ func VaultDisableKVv2(client *api.Client, path string, verbose bool) (err error)
 	return nil
 }
 
+// VaultPolicyExists reports, if the ACL policy exists.
+func VaultPolicyExists(client *api.Client, policyName string, verbose bool) (bool, error) {
+
+	policy, err := client.Sys().GetPolicy(policyName)
+	if err != nil {
+		return false, fmt.Errorf("failed to read policy %s: %v", policyName, err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultPolicyExists: policy %s exists: %t\n", policyName, policy != "")
+	}
+
+	return policy != "", nil
+}
+
+// VaultReadPolicy returns the content of the policy or an empty string, if the policy does not exist.
+func VaultReadPolicy(client *api.Client, policyName string, verbose bool) (string, error) {
+
+	policy, err := client.Sys().GetPolicy(policyName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read policy %s: %v", policyName, err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultReadPolicy: policy %s:%s\n", policyName, policy)
+	}
+
+	return policy, nil
+}
+
+// VaultRoleExists reports, if the AppRole of the group exists.
+func VaultRoleExists(client *api.Client, group string, verbose bool) (bool, error) {
+
+	secret, err := client.Logical().Read(fmt.Sprintf("auth/approle/role/%s", group))
+	if err != nil {
+		return false, fmt.Errorf("failed to read role %s: %v", group, err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultRoleExists: role %s exists: %t\n", group, secret != nil)
+	}
+
+	return secret != nil, nil
+}
+
+// VaultMountExists reports, if a secrets engine is enabled at the path.
+func VaultMountExists(client *api.Client, path string, verbose bool) (bool, error) {
+
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return false, fmt.Errorf("failed to list Vault mounts: %v", err)
+	}
+
+	// Vault paths always end with "/"
+	_, exists := mounts[path+"/"]
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultMountExists: mount %s exists: %t\n", path, exists)
+	}
+
+	return exists, nil
+}
+
+// VaultReadSecretData returns the data of the secret at the path of a KV v2 store or nil, if the secret does not exist.
+func VaultReadSecretData(client *api.Client, path string, verbose bool) (map[string]interface{}, error) {
+
+	secret, err := client.Logical().Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s: %v", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	data, _ := secret.Data["data"].(map[string]interface{})
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultReadSecretData: found %d keys at %s\n", len(data), path)
+	}
+
+	return data, nil
+}
+
+// VaultRemoveSecretKey removes one key of a secret in the vault.
+func VaultRemoveSecretKey(client *api.Client, path, key string, verbose bool) error {
+
+	secret, err := client.Logical().Read(path)
+	if err != nil {
+		return fmt.Errorf("failed to read existing secrets: %v", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil
+	}
+
+	existingData, _ := secret.Data["data"].(map[string]interface{})
+	if _, exists := existingData[key]; !exists {
+		return nil
+	}
+	delete(existingData, key)
+
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"data": existingData, // KV v2 requires the data field
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write updated secrets: %v", err)
+	}
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultRemoveSecretKey: Successful removed %s from %s", key, path)
+	}
+
+	return nil
+}
+
+// VaultListRoles returns the names of all AppRoles.
+func VaultListRoles(client *api.Client, verbose bool) (roles []string, err error) {
+
+	secret, err := client.Logical().List("auth/approle/role")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %v", err)
+	}
+
+	// no roles
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	for _, k := range keys {
+		if role, ok := k.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultListRoles: found roles %v\n", roles)
+	}
+
+	return roles, nil
+}
+
+// VaultListPolicies returns the names of all ACL policies.
+func VaultListPolicies(client *api.Client, verbose bool) (policies []string, err error) {
+
+	policies, err = client.Sys().ListPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policies: %v", err)
+	}
+	sort.Strings(policies)
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultListPolicies: found policies %v\n", policies)
+	}
+
+	return policies, nil
+}
+
+// VaultTokenRole returns the AppRole of the token of the client or an empty string, if the token is not from an
+// AppRole login.
+func VaultTokenRole(client *api.Client, verbose bool) (string, error) {
+
+	secret, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		return "", fmt.Errorf("failed to lookup token: %v", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return "", nil
+	}
+
+	meta, _ := secret.Data["meta"].(map[string]interface{})
+	role, _ := meta["role_name"].(string)
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultTokenRole: role of the token: %s\n", role)
+	}
+
+	return role, nil
+}
+
+// VaultListTenants returns the groups of the KV stores with the prefix. The KV store mounted at exclude is skipped.
+func VaultListTenants(client *api.Client, prefix, exclude string, verbose bool) (groups []string, err error) {
+
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Vault mounts: %v", err)
+	}
+
+	for mountPath := range mounts {
+		// Vault paths always end with "/"
+		name := strings.TrimSuffix(mountPath, "/")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		group := strings.TrimPrefix(name, prefix)
+		if group == "" || name == strings.TrimSuffix(exclude, "/") {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	if verbose {
+		log.Printf("DEBUG HCVAPI VaultListTenants: found groups %v\n", groups)
+	}
+
+	return groups, nil
+}
+
 // VaultUpdateSecret update one secret in the vault.
 func VaultUpdateSecret(client *api.Client, path, key, value string, verbose bool) error {
 	// Read existing secrets
@@ -0,0 +1,351 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// newVaultClient returns a Vault client for the test server.
+func newVaultClient(t *testing.T, handler http.HandlerFunc) *api.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("could not create Vault client: %v", err)
+	}
+	client.SetToken("token")
+	return client
+}
+
+func TestVaultListTenants(t *testing.T) {
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/mounts" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"data": {
+			"kv-clab-beta/": {"type": "kv"},
+			"kv-clab-alpha/": {"type": "kv"},
+			"kv-clab-dagobah/": {"type": "kv"},
+			"secret/": {"type": "kv"},
+			"sys/": {"type": "system"}}}`)
+	})
+
+	groups, err := VaultListTenants(client, DefaultKVPrefix, DefaultAdminMount, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(groups, ",") != "alpha,beta" {
+		t.Errorf("expected alpha,beta, got %v", groups)
+	}
+}
+
+func TestVaultExists(t *testing.T) {
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/policies/acl/alpha_read_policy":
+			fmt.Fprint(w, `{"data": {"name": "alpha_read_policy", "policy": "path \"kv-clab-alpha*\" {}"}}`)
+		case "/v1/auth/approle/role/alpha":
+			fmt.Fprint(w, `{"data": {"token_ttl": 3600}}`)
+		case "/v1/sys/mounts":
+			fmt.Fprint(w, `{"data": {"kv-clab-alpha/": {"type": "kv"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": []}`)
+		}
+	})
+
+	checks := []struct {
+		name  string
+		check func() (bool, error)
+		want  bool
+	}{
+		{"policy alpha", func() (bool, error) { return VaultPolicyExists(client, "alpha_read_policy", false) }, true},
+		{"policy beta", func() (bool, error) { return VaultPolicyExists(client, "beta_read_policy", false) }, false},
+		{"role alpha", func() (bool, error) { return VaultRoleExists(client, "alpha", false) }, true},
+		{"role beta", func() (bool, error) { return VaultRoleExists(client, "beta", false) }, false},
+		{"mount alpha", func() (bool, error) { return VaultMountExists(client, "kv-clab-alpha", false) }, true},
+		{"mount beta", func() (bool, error) { return VaultMountExists(client, "kv-clab-beta", false) }, false},
+	}
+
+	for _, c := range checks {
+		got, err := c.check()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %v; want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestVaultCreateSecretID(t *testing.T) {
+	accessors := `"acc-old"`
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "LIST" || r.URL.Query().Get("list") == "true" {
+			fmt.Fprintf(w, `{"data": {"keys": [%s]}}`, accessors)
+			return
+		}
+		if r.URL.Path != "/v1/auth/approle/role/alpha/secret-id" || r.Method != http.MethodPut && r.Method != http.MethodPost {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if ttl := r.Header.Get("X-Vault-Wrap-TTL"); ttl != "" {
+			if ttl != "5m0s" {
+				t.Errorf("unexpected wrap TTL: %s", ttl)
+			}
+			accessors = `"acc-old", "acc-unwrapped"`
+			fmt.Fprint(w, `{"wrap_info": {"token": "hvs.wrapped", "ttl": 300, "wrapped_accessor": "acc-wrapped"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"secret_id": "new-secret", "secret_id_accessor": "acc-new"}}`)
+	})
+
+	secretID, accessor, wrapToken, err := VaultCreateSecretID(client, "alpha", 0, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secretID != "new-secret" || accessor != "acc-new" || wrapToken != "" {
+		t.Errorf("unexpected plain secret ID: %s %s %s", secretID, accessor, wrapToken)
+	}
+
+	secretID, accessor, wrapToken, err = VaultCreateSecretID(client, "alpha", 5*time.Minute, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secretID != "" || accessor != "acc-unwrapped" || wrapToken != "hvs.wrapped" {
+		t.Errorf("unexpected wrapped secret ID: %s %s %s", secretID, accessor, wrapToken)
+	}
+	if client.Token() != "token" {
+		t.Errorf("expected token of the client to be unchanged, got %s", client.Token())
+	}
+}
+
+func TestVaultSecretIDAccessors(t *testing.T) {
+	var destroyed []string
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/role/alpha/secret-id":
+			if r.URL.Query().Get("list") != "true" {
+				t.Errorf("expected list request, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"data": {"keys": ["acc-1", "acc-2"]}}`)
+		case "/v1/auth/approle/role/alpha/secret-id-accessor/destroy":
+			var payload map[string]string
+			json.NewDecoder(r.Body).Decode(&payload)
+			destroyed = append(destroyed, payload["secret_id_accessor"])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	})
+
+	accessors, err := VaultListSecretIDAccessors(client, "alpha", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(accessors, ",") != "acc-1,acc-2" {
+		t.Errorf("unexpected accessors: %v", accessors)
+	}
+
+	if err := VaultDestroySecretIDAccessor(client, "alpha", "acc-1", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(destroyed) != 1 || destroyed[0] != "acc-1" {
+		t.Errorf("unexpected destroyed accessors: %v", destroyed)
+	}
+}
+
+func TestVaultRemoveSecretKey(t *testing.T) {
+	var written map[string]interface{}
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv-clab-alpha/data/approle_output" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"data": {"data": {"role_id": "role", "secret_id": "secret"}}}`)
+			return
+		}
+		var payload struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		written = payload.Data
+		fmt.Fprint(w, `{"data": {"version": 2}}`)
+	})
+
+	if err := VaultRemoveSecretKey(client, "kv-clab-alpha/data/approle_output", "secret_id", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(written) != 1 || written["role_id"] != "role" {
+		t.Errorf("expected only role_id to be kept, got %v", written)
+	}
+}
+
+func TestRoleOptionsRoleData(t *testing.T) {
+	data := RoleOptionsType{}.roleData("alpha_read_policy", false)
+	if len(data) != 3 || data["token_ttl"] != 3600 || data["token_max_ttl"] != 14400 {
+		t.Errorf("expected only the defaults, got %v", data)
+	}
+
+	options := RoleOptionsType{
+		SecretIDTTL:        24 * time.Hour,
+		SecretIDNumUses:    10,
+		SecretIDBoundCIDRs: []string{NetworkCIDR("172.1.22.0")},
+		TokenBoundCIDRs:    []string{"172.1.22.0/24", "10.0.0.0/8"},
+		TokenTTL:           30 * time.Minute,
+		TokenType:          "batch",
+		Period:             time.Hour,
+		Policies:           []string{"monitoring"},
+	}
+	data = options.roleData("alpha_read_policy", false)
+	if data["secret_id_ttl"] != 86400 || data["secret_id_num_uses"] != 10 {
+		t.Errorf("unexpected secret ID constraints: %v", data)
+	}
+	if data["token_ttl"] != 1800 || data["token_max_ttl"] != 14400 || data["token_type"] != "batch" || data["token_period"] != 3600 {
+		t.Errorf("unexpected token options: %v", data)
+	}
+	if policies, _ := data["policies"].([]string); len(policies) != 2 || policies[0] != "alpha_read_policy" || policies[1] != "monitoring" {
+		t.Errorf("unexpected policies: %v", data["policies"])
+	}
+	if cidrs, _ := data["secret_id_bound_cidrs"].([]string); len(cidrs) != 1 || cidrs[0] != "172.1.22.0/24" {
+		t.Errorf("unexpected secret_id_bound_cidrs: %v", data["secret_id_bound_cidrs"])
+	}
+	if cidrs, _ := data["token_bound_cidrs"].([]string); len(cidrs) != 2 {
+		t.Errorf("unexpected token_bound_cidrs: %v", data["token_bound_cidrs"])
+	}
+}
+
+func TestRoleOptionsRoleData_Update(t *testing.T) {
+	data := RoleOptionsType{TokenMaxTTL: 8 * time.Hour}.roleData("alpha_read_policy", true)
+	if len(data) != 1 || data["token_max_ttl"] != 28800 {
+		t.Errorf("expected only token_max_ttl to change, got %v", data)
+	}
+
+	data = RoleOptionsType{Policies: []string{}, TokenBoundCIDRs: []string{}}.roleData("alpha_read_policy", true)
+	if policies, _ := data["policies"].([]string); len(policies) != 1 || policies[0] != "alpha_read_policy" {
+		t.Errorf("expected additional policies to be removed, got %v", data["policies"])
+	}
+	if cidrs, ok := data["token_bound_cidrs"].([]string); !ok || len(cidrs) != 0 {
+		t.Errorf("expected token binding to be removed, got %v", data["token_bound_cidrs"])
+	}
+}
+
+func TestVaultUpdateRole(t *testing.T) {
+	var written map[string]interface{}
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/approle/role/alpha" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&written)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := VaultUpdateRole(client, "alpha", "alpha_read_policy", RoleOptionsType{TokenType: "service"}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(written) != 1 || written["token_type"] != "service" {
+		t.Errorf("unexpected role data: %v", written)
+	}
+
+	if err := VaultUpdateRole(client, "alpha", "alpha_read_policy", RoleOptionsType{}, false); err == nil {
+		t.Error("expected error without options")
+	}
+}
+
+func TestVaultGetSecrets(t *testing.T) {
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv-lab2-admin/data/suma2" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"data": {"login": "admin"}}}`)
+	})
+
+	secrets, err := VaultGetSecrets(client, "", "kv-lab2-admin", "suma2", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secrets["login"] != "admin" {
+		t.Errorf("unexpected secrets: %v", secrets)
+	}
+}
+
+func TestVaultListRolesAndPolicies(t *testing.T) {
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/role":
+			if r.Method != "LIST" && r.URL.Query().Get("list") != "true" {
+				t.Errorf("unexpected method: %s", r.Method)
+			}
+			fmt.Fprint(w, `{"data": {"keys": ["beta", "alpha"]}}`)
+		case "/v1/sys/policies/acl":
+			fmt.Fprint(w, `{"data": {"keys": ["root", "default", "alpha_read_policy"]}}`)
+		case "/v1/auth/token/lookup-self":
+			fmt.Fprint(w, `{"data": {"meta": {"role_name": "admin"}}}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	roles, err := VaultListRoles(client, false)
+	if err != nil || strings.Join(roles, ",") != "alpha,beta" {
+		t.Errorf("unexpected roles: %v %v", roles, err)
+	}
+
+	policies, err := VaultListPolicies(client, false)
+	if err != nil || strings.Join(policies, ",") != "alpha_read_policy,default,root" {
+		t.Errorf("unexpected policies: %v %v", policies, err)
+	}
+
+	role, err := VaultTokenRole(client, false)
+	if err != nil || role != "admin" {
+		t.Errorf("unexpected role of the token: %q %v", role, err)
+	}
+}
+
+func TestVaultReadRole(t *testing.T) {
+	client := newVaultClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/role/alpha":
+			fmt.Fprint(w, `{"data": {
+				"token_ttl": 3600,
+				"token_max_ttl": 14400,
+				"token_period": 0,
+				"token_type": "default",
+				"token_policies": ["alpha_read_policy", "monitoring"],
+				"token_bound_cidrs": ["172.1.22.0/24"],
+				"secret_id_ttl": 86400,
+				"secret_id_num_uses": 5}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": []}`)
+		}
+	})
+
+	options, found, err := VaultReadRole(client, "alpha", false)
+	if err != nil || !found {
+		t.Fatalf("expected role, got found %v error %v", found, err)
+	}
+	if options.TokenTTL != time.Hour || options.TokenMaxTTL != 4*time.Hour || options.Period != 0 || options.TokenType != "default" {
+		t.Errorf("unexpected token options: %+v", options)
+	}
+	if options.SecretIDTTL != 24*time.Hour || options.SecretIDNumUses != 5 {
+		t.Errorf("unexpected secret ID options: %+v", options)
+	}
+	if strings.Join(options.Policies, ",") != "alpha_read_policy,monitoring" || strings.Join(options.TokenBoundCIDRs, ",") != "172.1.22.0/24" {
+		t.Errorf("unexpected lists: %+v", options)
+	}
+
+	if _, found, err := VaultReadRole(client, "beta", false); err != nil || found {
+		t.Errorf("expected missing role, got found %v error %v", found, err)
+	}
+}
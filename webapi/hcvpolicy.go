@@ -0,0 +1,92 @@
+package webapi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// PolicyDataType are the variables of a policy template.
+type PolicyDataType struct {
+	Group      string
+	Role       string // name of the AppRole and the read policy of the group
+	Network    string
+	KVPrefix   string
+	AdminMount string
+	AdminPath  string
+}
+
+// DefaultPolicyTemplate is the built-in template of the read policy of a group.
+const DefaultPolicyTemplate = `path "{{.KVPrefix}}{{.Group}}*" {
+		capabilities = ["list", "read"]
+	}
+path "{{.AdminMount}}/data/{{.AdminPath}}" {
+	capabilities = ["list", "read"]
+}
+path "sys/policies/acl/{{.Role}}_read_policy" {
+	capabilities = ["read"]
+}
+path "auth/token/lookup-self" {
+  capabilities = ["read"]
+}`
+
+// ReadPolicyTemplate reads the policy template from the file or returns the built-in template for an empty filename.
+func ReadPolicyTemplate(filename string) (string, error) {
+	if filename == "" {
+		return DefaultPolicyTemplate, nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("could not read policy template: %v", err)
+	}
+
+	return string(content), nil
+}
+
+// RenderPolicy renders the policy of a group from the template. Unknown variables are an error.
+func RenderPolicy(policyTemplate string, data PolicyDataType) (string, error) {
+
+	t, err := template.New("policy").Option("missingkey=error").Parse(policyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("could not parse policy template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render policy template: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// PolicyDiff compares the lines of the rendered and the live policy. Lines only in the rendered policy are prefixed
+// with "-", lines only in the live policy with "+". Leading and trailing whitespace is ignored.
+func PolicyDiff(rendered, live string) (diff []string) {
+
+	count := make(map[string]int)
+	for _, line := range strings.Split(live, "\n") {
+		count[strings.TrimSpace(line)]++
+	}
+
+	for _, line := range strings.Split(rendered, "\n") {
+		line = strings.TrimSpace(line)
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		diff = append(diff, "- "+line)
+	}
+
+	for _, line := range strings.Split(live, "\n") {
+		line = strings.TrimSpace(line)
+		if count[line] > 0 {
+			count[line]--
+			diff = append(diff, "+ "+line)
+		}
+	}
+
+	return diff
+}
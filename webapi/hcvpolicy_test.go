@@ -0,0 +1,77 @@
+package webapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPolicy_Default(t *testing.T) {
+	// the policy, which was created before the policy templates
+	want := fmt.Sprintf(
+		`path "kv-clab-%s*" {
+		capabilities = ["list", "read"]
+	}
+path "kv-clab-dagobah/data/suma" {
+	capabilities = ["list", "read"]
+}
+path "sys/policies/acl/%s_read_policy" {
+	capabilities = ["read"]
+}
+path "auth/token/lookup-self" {
+  capabilities = ["read"]
+}`, "alpha", "alpha")
+
+	got, err := RenderPolicy(DefaultPolicyTemplate, PolicyDataType{Group: "alpha", Role: "alpha", Network: "172.1.22.0", KVPrefix: DefaultKVPrefix, AdminMount: DefaultAdminMount, AdminPath: DefaultAdminPath})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("default policy differs:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderPolicy_Errors(t *testing.T) {
+	if _, err := RenderPolicy(`path "{{.Group" {}`, PolicyDataType{}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+	if _, err := RenderPolicy(`path "{{.Tenant}}" {}`, PolicyDataType{}); err == nil {
+		t.Error("expected error for unknown variable")
+	}
+}
+
+func TestReadPolicyTemplate(t *testing.T) {
+	got, err := ReadPolicyTemplate("")
+	if err != nil || got != DefaultPolicyTemplate {
+		t.Errorf("expected built-in template, got %q %v", got, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "policy.tmpl")
+	if err := os.WriteFile(file, []byte(`path "{{.KVPrefix}}{{.Group}}/*" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadPolicyTemplate(file)
+	if err != nil || !strings.Contains(got, "{{.Group}}") {
+		t.Errorf("unexpected template %q %v", got, err)
+	}
+
+	if _, err := ReadPolicyTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestPolicyDiff(t *testing.T) {
+	rendered := "path \"a\" {\n  capabilities = [\"read\"]\n}\npath \"b\" {\n  capabilities = [\"read\"]\n}"
+
+	if diff := PolicyDiff(rendered, strings.ReplaceAll(rendered, "  ", "\t")); len(diff) != 0 {
+		t.Errorf("expected no difference for other indentation, got %v", diff)
+	}
+
+	live := strings.Replace(rendered, `path "b"`, `path "c"`, 1)
+	diff := PolicyDiff(rendered, live)
+	if len(diff) != 2 || diff[0] != `- path "b" {` || diff[1] != `+ path "c" {` {
+		t.Errorf("unexpected diff: %v", diff)
+	}
+}
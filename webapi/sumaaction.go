@@ -0,0 +1,169 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ActionStatusType is the state of a scheduled action on its systems.
+type ActionStatusType struct {
+	ActionID   int          `json:"action_id"`
+	InProgress []SystemType `json:"in_progress"`
+	Completed  []SystemType `json:"completed"`
+	Failed     []SystemType `json:"failed"`
+}
+
+// Done reports, if the action is finished on all systems.
+func (a ActionStatusType) Done() bool {
+	return len(a.InProgress) == 0
+}
+
+// Systems returns all systems of the action.
+func (a ActionStatusType) Systems() (systems []SystemType) {
+	systems = append(systems, a.InProgress...)
+	systems = append(systems, a.Completed...)
+	systems = append(systems, a.Failed...)
+	return systems
+}
+
+var sumaListActionSystems = func(sessioncookie, susemgr, apimethod string, actionID int, verbose bool) (systems []SystemType, err error) {
+
+	type ResultActionSystem struct {
+		ServerID   int    `json:"server_id"`
+		ServerName string `json:"server_name"`
+	}
+
+	var rsp []ResultActionSystem
+	err = sumaGet(sessioncookie, susemgr, apimethod, url.Values{"actionId": {strconv.Itoa(actionID)}}, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range rsp {
+		systems = append(systems, SystemType{ID: s.ServerID, Name: s.ServerName})
+	}
+
+	return systems, nil
+}
+
+// SumaGetActionStatus get the systems, on which the action is in progress, completed or failed.
+func SumaGetActionStatus(sessioncookie, susemgr string, actionID int, verbose bool) (status ActionStatusType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaGetActionStatus: Enter function")
+		log.Println("DEBUG SUMAAPI SumaGetActionStatus: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaGetActionStatus: Leave function")
+	}
+
+	status.ActionID = actionID
+
+	status.InProgress, err = sumaListActionSystems(sessioncookie, susemgr, "/schedule/listInProgressSystems", actionID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get systems in progress of action %d: %v", actionID, err)
+	}
+
+	status.Completed, err = sumaListActionSystems(sessioncookie, susemgr, "/schedule/listCompletedSystems", actionID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get completed systems of action %d: %v", actionID, err)
+	}
+
+	status.Failed, err = sumaListActionSystems(sessioncookie, susemgr, "/schedule/listFailedSystems", actionID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get failed systems of action %d: %v", actionID, err)
+	}
+
+	return status, nil
+}
+
+// SumaWaitForAction polls the action until it is finished on all systems or the timeout is reached.
+func SumaWaitForAction(sessioncookie, susemgr string, actionID int, timeout time.Duration, verbose bool) (status ActionStatusType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaWaitForAction: Enter function")
+		log.Println("DEBUG SUMAAPI SumaWaitForAction: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaWaitForAction: Leave function")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err = SumaGetActionStatus(sessioncookie, susemgr, actionID, verbose)
+		if err != nil {
+			return status, err
+		}
+
+		if status.Done() {
+			return status, nil
+		}
+
+		if time.Now().After(deadline) {
+			return status, fmt.Errorf("action %d did not finish on all systems within %v", actionID, timeout)
+		}
+
+		if verbose {
+			log.Printf("DEBUG SUMAAPI SumaWaitForAction: action %d in progress on %d systems\n", actionID, len(status.InProgress))
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// SumaCheckActionSystems ensures, that all systems of the action belong to the group and the permitted network,
+// so that a tenant could only inspect or cancel his own actions.
+func SumaCheckActionSystems(sessioncookie, susemgr string, status ActionStatusType, group, network string, verbose bool) (err error) {
+
+	systems := status.Systems()
+	if len(systems) == 0 {
+		return fmt.Errorf("no systems found for action %d", status.ActionID)
+	}
+
+	members, err := sumaListGroupSystems(sessioncookie, susemgr, group, verbose)
+	if err != nil {
+		return err
+	}
+
+	for _, system := range systems {
+		member := false
+		for _, m := range members {
+			if m.ID == system.ID {
+				member = true
+			}
+		}
+		if !member {
+			return fmt.Errorf("action %d was not started for the group %s", status.ActionID, group)
+		}
+
+		ip, err := sumaGetSystemIP(sessioncookie, susemgr, system.ID, verbose)
+		if err != nil {
+			return err
+		}
+		if !isSystemInNetwork(ip, network) {
+			return fmt.Errorf("action %d was not started for the permitted network of the group", status.ActionID)
+		}
+	}
+
+	return nil
+}
+
+// SumaCancelAction cancel a scheduled action.
+func SumaCancelAction(sessioncookie, susemgr string, actionID int, verbose bool) (err error) {
+
+	type CancelActions struct {
+		ActionIds []int `json:"actionIds"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaCancelAction: Enter function")
+		log.Println("DEBUG SUMAAPI SumaCancelAction: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaCancelAction: Leave function")
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/schedule/cancelActions", CancelActions{ActionIds: []int{actionID}}, nil, verbose)
+	if err != nil {
+		log.Printf("could not cancel action %d: %v\n", actionID, err)
+		return err
+	}
+
+	return nil
+}
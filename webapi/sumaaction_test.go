@@ -0,0 +1,98 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newActionServer(t *testing.T, inProgress string, cancelled *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/schedule/listInProgressSystems":
+			fmt.Fprintf(w, `{"success": true, "result": [%s]}`, inProgress)
+		case "/rhn/manager/api/schedule/listCompletedSystems":
+			fmt.Fprint(w, `{"success": true, "result": [{"server_id": 1, "server_name": "a.example.com"}]}`)
+		case "/rhn/manager/api/schedule/listFailedSystems":
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/schedule/cancelActions":
+			var payload struct {
+				ActionIds []int `json:"actionIds"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*cancelled = payload.ActionIds
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestSumaWaitForAction_Timeout(t *testing.T) {
+	oldPollInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() { pollInterval = oldPollInterval }()
+
+	var cancelled []int
+	server := newActionServer(t, `{"server_id": 2, "server_name": "b.example.com"}`, &cancelled)
+	defer server.Close()
+
+	status, err := SumaWaitForAction("cookie", server.URL, 500, 10*time.Millisecond, false)
+	if err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if status.Done() || len(status.InProgress) != 1 || len(status.Completed) != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestSumaWaitForAction_Done(t *testing.T) {
+	var cancelled []int
+	server := newActionServer(t, "", &cancelled)
+	defer server.Close()
+
+	status, err := SumaWaitForAction("cookie", server.URL, 500, time.Second, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Done() || len(status.Systems()) != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestSumaCheckActionSystems(t *testing.T) {
+	patchGroupSystems(t, map[string]string{"a.example.com": "192.168.1.10"})
+
+	own := ActionStatusType{ActionID: 500, Completed: []SystemType{{ID: 1, Name: "a.example.com"}}}
+	if err := SumaCheckActionSystems("cookie", "http://dummy", own, "group", "192.168.1.0", false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	foreign := ActionStatusType{ActionID: 501, Completed: []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 7, Name: "x.example.com"}}}
+	err := SumaCheckActionSystems("cookie", "http://dummy", foreign, "group", "192.168.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "was not started for the group") {
+		t.Errorf("expected group error, got %v", err)
+	}
+
+	err = SumaCheckActionSystems("cookie", "http://dummy", ActionStatusType{ActionID: 502}, "group", "192.168.1.0", false)
+	if err == nil {
+		t.Error("expected error for action without systems")
+	}
+}
+
+func TestSumaCancelAction(t *testing.T) {
+	var cancelled []int
+	server := newActionServer(t, "", &cancelled)
+	defer server.Close()
+
+	if err := SumaCancelAction("cookie", server.URL, 500, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0] != 500 {
+		t.Errorf("expected action 500 to be cancelled, got %v", cancelled)
+	}
+}
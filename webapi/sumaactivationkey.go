@@ -0,0 +1,174 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+)
+
+var sumaGetSystemGroupID = func(sessioncookie, susemgrurl, group string, verbose bool) (id int, err error) {
+
+	type ResultSystemGroupDetails struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var rsp ResultSystemGroupDetails
+	err = sumaGet(sessioncookie, susemgrurl, "/systemgroup/getDetails", url.Values{"systemGroupName": {group}}, &rsp, verbose)
+	if err != nil {
+		log.Printf("could not get details of systemgroup %s: %v\n", group, err)
+		return -1, err
+	}
+
+	return rsp.ID, nil
+}
+
+var sumaCreateSystemGroup = func(sessioncookie, susemgrurl, group string, verbose bool) (err error) {
+
+	type CreateSystemGroup struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	payload := CreateSystemGroup{
+		Name:        group,
+		Description: fmt.Sprintf("Systemgroup of %s", group),
+	}
+
+	err = sumaPost(sessioncookie, susemgrurl, "/systemgroup/create", payload, nil, verbose)
+	if err != nil {
+		log.Printf("could not create systemgroup %s: %v\n", group, err)
+		return err
+	}
+
+	return nil
+}
+
+var sumaFindActivationKey = func(sessioncookie, susemgrurl, group string, verbose bool) (key string, err error) {
+
+	type ResultActivationKey struct {
+		Key         string `json:"key"`
+		Description string `json:"description"`
+	}
+
+	var rsp []ResultActivationKey
+	err = sumaGet(sessioncookie, susemgrurl, "/activationkey/listActivationKeys", nil, &rsp, verbose)
+	if err != nil {
+		log.Printf("could not list activation keys: %v\n", err)
+		return "", err
+	}
+
+	// SUSE Manager prefix the key with the organization ID, the key of another group may end with -<group>
+	pattern := regexp.MustCompile(`^\d+-` + regexp.QuoteMeta(group) + `$`)
+	for _, k := range rsp {
+		if pattern.MatchString(k.Key) {
+			return k.Key, nil
+		}
+	}
+
+	return "", nil
+}
+
+// SumaCreateActivationKey create an activation key for the group, which add's registered systems to the
+// SUSE Manager SystemGroup of the group. If the SystemGroup does not exist, it is created.
+func SumaCreateActivationKey(sessioncookie, susemgrurl, group, basechannel string, verbose bool) (key string, err error) {
+
+	type CreateActivationKey struct {
+		Key              string   `json:"key"`
+		Description      string   `json:"description"`
+		BaseChannelLabel string   `json:"baseChannelLabel"`
+		Entitlements     []string `json:"entitlements"`
+		UniversalDefault bool     `json:"universalDefault"`
+	}
+
+	type AddServerGroups struct {
+		Key            string `json:"key"`
+		ServerGroupIds []int  `json:"serverGroupIds"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaCreateActivationKey: Enter function")
+		log.Println("DEBUG SUMAAPI SumaCreateActivationKey: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaCreateActivationKey: Leave function")
+	}
+
+	if !sumaCheckSystemGroup(sessioncookie, group, susemgrurl, verbose) {
+		err = sumaCreateSystemGroup(sessioncookie, susemgrurl, group, verbose)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	groupID, err := sumaGetSystemGroupID(sessioncookie, susemgrurl, group, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	key, err = sumaFindActivationKey(sessioncookie, susemgrurl, group, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	if key != "" {
+		log.Printf("activation key %s already exists in SUMA.\n", key)
+	} else {
+		payload := CreateActivationKey{
+			Key:              group,
+			Description:      fmt.Sprintf("Activation key of %s", group),
+			BaseChannelLabel: basechannel,
+			Entitlements:     []string{},
+			UniversalDefault: false,
+		}
+
+		err = sumaPost(sessioncookie, susemgrurl, "/activationkey/create", payload, &key, verbose)
+		if err != nil {
+			log.Printf("could not create activation key for %s: %v\n", group, err)
+			return "", err
+		}
+	}
+
+	payload := AddServerGroups{
+		Key:            key,
+		ServerGroupIds: []int{groupID},
+	}
+
+	err = sumaPost(sessioncookie, susemgrurl, "/activationkey/addServerGroups", payload, nil, verbose)
+	if err != nil {
+		log.Printf("could not add systemgroup %s to activation key %s: %v\n", group, key, err)
+		return key, err
+	}
+
+	if verbose {
+		log.Printf("DEBUG SUMAAPI SumaCreateActivationKey: activation key = %s\n", key)
+	}
+
+	return key, nil
+}
+
+// SumaFindActivationKey returns the activation key of the group or an empty string, if the group has no key.
+func SumaFindActivationKey(sessioncookie, susemgrurl, group string, verbose bool) (key string, err error) {
+	return sumaFindActivationKey(sessioncookie, susemgrurl, group, verbose)
+}
+
+// SumaDeleteActivationKey delete an activation key from the SUSE Manager.
+func SumaDeleteActivationKey(sessioncookie, susemgrurl, key string, verbose bool) (err error) {
+
+	type DeleteActivationKey struct {
+		Key string `json:"key"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaDeleteActivationKey: Enter function")
+		log.Println("DEBUG SUMAAPI SumaDeleteActivationKey: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaDeleteActivationKey: Leave function")
+	}
+
+	err = sumaPost(sessioncookie, susemgrurl, "/activationkey/delete", DeleteActivationKey{Key: key}, nil, verbose)
+	if err != nil {
+		log.Printf("could not delete activation key %s: %v\n", key, err)
+		return err
+	}
+
+	return nil
+}
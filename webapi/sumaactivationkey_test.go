@@ -0,0 +1,97 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSumaCreateActivationKey_Success(t *testing.T) {
+	groupCreated := false
+	var addedGroups []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/systemgroup/listAllGroups":
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/systemgroup/create":
+			groupCreated = true
+			fmt.Fprint(w, `{"success": true, "result": {"id": 7, "name": "testgroup"}}`)
+		case "/rhn/manager/api/systemgroup/getDetails":
+			if r.URL.Query().Get("systemGroupName") != "testgroup" {
+				t.Errorf("unexpected systemGroupName: %s", r.URL.Query().Get("systemGroupName"))
+			}
+			fmt.Fprint(w, `{"success": true, "result": {"id": 7, "name": "testgroup"}}`)
+		case "/rhn/manager/api/activationkey/listActivationKeys":
+			fmt.Fprint(w, `{"success": true, "result": [{"key": "1-other"}]}`)
+		case "/rhn/manager/api/activationkey/create":
+			fmt.Fprint(w, `{"success": true, "result": "1-testgroup"}`)
+		case "/rhn/manager/api/activationkey/addServerGroups":
+			var payload struct {
+				Key            string `json:"key"`
+				ServerGroupIds []int  `json:"serverGroupIds"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			addedGroups = payload.ServerGroupIds
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	key, err := SumaCreateActivationKey("cookie", server.URL, "testgroup", "sle-product-sles15-sp6-pool-x86_64", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key != "1-testgroup" {
+		t.Errorf("expected key 1-testgroup, got %s", key)
+	}
+	if !groupCreated {
+		t.Error("expected systemgroup to be created")
+	}
+	if len(addedGroups) != 1 || addedGroups[0] != 7 {
+		t.Errorf("expected systemgroup 7 added to key, got %v", addedGroups)
+	}
+}
+
+func TestSumaDeleteActivationKey_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": false, "message": "Could not find activation key"}`)
+	}))
+	defer server.Close()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	err := SumaDeleteActivationKey("cookie", server.URL, "1-testgroup", false)
+	if err == nil {
+		t.Fatal("expected error for unknown activation key, got nil")
+	}
+}
+
+func TestSumaFindActivationKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rhn/manager/api/activationkey/listActivationKeys" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"success": true, "result": [{"key": "1-foo-lab"}, {"key": "1-other-lab"}, {"key": "lab"}, {"key": "1-lab"}]}`)
+	}))
+	defer server.Close()
+
+	key, err := SumaFindActivationKey("cookie", server.URL, "lab", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key != "1-lab" {
+		t.Errorf("expected 1-lab, got %q", key)
+	}
+
+	// the key of tenant foo-lab ends with -lab, but is not the key of lab
+	key, err = SumaFindActivationKey("cookie", server.URL, "other", false)
+	if err != nil || key != "" {
+		t.Errorf("expected no key for other, got %q %v", key, err)
+	}
+}
@@ -3,6 +3,7 @@ package webapi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -415,112 +416,100 @@ func SumaAddSystem(sessioncookie, susemgr, hostname, group, network string, verb
 
 }
 
-// SumaDeleteSystem delete a System from the SUSE Manager. This implies, that it is also deleted from the SUSE Manager SystemGroup.
-// To ensure, that DeleteSystem could not delete other Systems from o differen IP range, the procedure check if the IP belongs
-// to the IP range we get from hashicorp vault.
-func SumaDeleteSystem(sessioncookie, susemgr, hostname, network string, verbose bool) (statsucode int, err error) {
+// Cleanup types of a system deletion.
+const (
+	CleanupFailOnError = "FAIL_ON_CLEANUP_ERR" // fail in case of a cleanup error
+	CleanupNone        = "NO_CLEANUP"          // do not cleanup, just delete
+	CleanupForce       = "FORCE_DELETE"        // try cleanup first, but delete the system anyway in case of an error
+)
+
+var sumaDeleteSystemByID = func(sessioncookie, susemgr string, id int, cleanupType string, verbose bool) (err error) {
 
 	type DeleteSystemType struct {
 		ServerID    int    `json:"sid"`
 		CleanupType string `json:"cleanupType"`
 	}
 
+	// Create the request payload
+	DeleteSystemPayload := DeleteSystemType{
+		ServerID:    id,
+		CleanupType: cleanupType,
+	}
+
+	return sumaPost(sessioncookie, susemgr, "/system/deleteSystem", DeleteSystemPayload, nil, verbose)
+}
+
+// isSumaFault reports, if the SUSE Manager API refused the request. Transport and HTTP errors are no faults.
+func isSumaFault(err error) bool {
+	var apiErr *sumaAPIError
+	return errors.As(err, &apiErr)
+}
+
+// SumaDeleteSystem delete a System from the SUSE Manager. This implies, that it is also deleted from the SUSE Manager SystemGroup.
+// To ensure, that DeleteSystem could not delete other Systems from o differen IP range, the procedure check if the IP belongs
+// to the IP range we get from hashicorp vault.
+// With the cleanupType FORCE_DELETE the system is first deleted with cleanup. If the SUSE Manager refuses this delete, the
+// procedure falls back to a force delete, as requested by the cleanupType, and reports this with forced. Transport and
+// HTTP errors are returned without fallback.
+func SumaDeleteSystem(sessioncookie, susemgr, hostname, network, cleanupType string, verbose bool) (statsucode int, forced bool, err error) {
+
 	if verbose {
 		log.Println("DEBUG SUMAAPI SumeDeleteSystem: Enter function")
 		log.Println("DEBUG SUMAAPI SumeDeleteSystem: ==============")
 		defer log.Println("DEBUG SUMAAPI SumeDeleteSystem: Leave function")
 	}
 
+	switch cleanupType {
+	case CleanupFailOnError, CleanupNone, CleanupForce:
+	default:
+		return -1, false, fmt.Errorf("invalid cleanup type %s", cleanupType)
+	}
+
 	foundID, err := sumaGetSystemID(sessioncookie, susemgr, hostname, verbose)
 	if err != nil {
-		return -1, err
+		return -1, false, err
 	}
 
 	if foundID == 0 {
-		return -1, fmt.Errorf("did not find the system in SUSE Manager")
+		return -1, false, fmt.Errorf("did not find the system in SUSE Manager")
 	}
 
 	foundIP, err := sumaGetSystemIP(sessioncookie, susemgr, foundID, verbose)
 	if err != nil {
 		log.Printf("Could not get IP, errorcode: %v", err)
-		return -1, err
+		return -1, false, err
 	}
 
 	if foundIP == "" {
-		return -1, fmt.Errorf("did not find the system ID %d in SUSE Manager", foundID)
+		return -1, false, fmt.Errorf("did not find the system ID %d in SUSE Manager", foundID)
 	}
 
 	isValid := isSystemInNetwork(foundIP, network)
 
 	if !isValid {
-		return -1, fmt.Errorf("%s cannot be deleted, the system does not belong to the permitted network of the group", hostname)
+		return -1, false, fmt.Errorf("%s cannot be deleted, the system does not belong to the permitted network of the group", hostname)
 	}
 
-	// Define the API endpoint
-	apiURL := fmt.Sprintf("%s%s", susemgr, "/rhn/manager/api")
-	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaDeleteSystem: apiURL =  %s\n", apiURL)
-	}
-
-	apiDeleteSystems := fmt.Sprintf("%s%s", apiURL, "/system/deleteSystem")
-	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaDeleteSystem: apiMethod = %s\n", apiDeleteSystems)
-	}
-
-	// Create the authentication request payload
-	DeleteSystemPayload := DeleteSystemType{
-		ServerID:    foundID,
-		CleanupType: "FORCE_DELETE",
-	}
-
-	// Marshal the payload to JSON
-	payloadBytes, err := json.Marshal(DeleteSystemPayload)
-	if err != nil {
-		log.Printf("error marshalling payload: %v\n", err)
-		return -1, err
-	}
-
-	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaDeleteSystem: Paylod =  %v\n", string(payloadBytes))
-	}
-
-	// Create an HTTP POST request
-	req, err := http.NewRequest(http.MethodPost, apiDeleteSystems, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		log.Printf("error creating request: %v\n", err)
-		return -1, err
-	}
-
-	// Add headers
-	req.Header.Set("Content-Type", "application/json")
-	req.AddCookie(&http.Cookie{
-		Name:  "pxt-session-cookie",
-		Value: sessioncookie,
-	})
-
-	// Send the request using the HTTP client
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("error sending request: %v\n", err)
-		return -1, err
-	}
-
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("error closing response body: %v\n", err)
+	if cleanupType == CleanupForce {
+		err = sumaDeleteSystemByID(sessioncookie, susemgr, foundID, CleanupFailOnError, verbose)
+		if err == nil {
+			return http.StatusOK, false, nil
 		}
-	}()
-
-	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaDeleteSystem: Delete Node: %v\n", resp)
+		if !isSumaFault(err) {
+			log.Printf("could not delete %s: %v\n", hostname, err)
+			return -1, false, err
+		}
+		log.Printf("cleanup of %s failed, forced delete with %s: %v\n", hostname, CleanupForce, err)
+		forced = true
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return -1, fmt.Errorf("HTTP Request failed: HTTP/%d", resp.StatusCode)
+	err = sumaDeleteSystemByID(sessioncookie, susemgr, foundID, cleanupType, verbose)
+	if err != nil {
+		log.Printf("could not delete %s: %v\n", hostname, err)
+		return -1, forced, err
 	}
 
-	return resp.StatusCode, nil
+	return http.StatusOK, forced, nil
 
 }
 
@@ -789,6 +778,11 @@ var sumaCheckUser = func(sessioncookie, group, susemgrurl string, verbose bool)
 	return false
 }
 
+// SumaUserExists reports, if the user exists in the suse manager.
+func SumaUserExists(sessioncookie, group, susemgrurl string, verbose bool) bool {
+	return sumaCheckUser(sessioncookie, group, susemgrurl, verbose)
+}
+
 // SumaAddUser add a user to the suse manager.
 func SumaAddUser(sessioncookie, group, grouppassword, susemgrurl string, verbose bool) (statuscode int, err error) {
 
@@ -841,8 +835,12 @@ func SumaAddUser(sessioncookie, group, grouppassword, susemgrurl string, verbose
 		return 1, err
 	}
 
+	// the password is never logged
 	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaAddUser: Payload =  %v\n", string(payloadBytes))
+		redacted := AddUserPayload
+		redacted.Password = "#########"
+		redactedBytes, _ := json.Marshal(redacted)
+		log.Printf("DEBUG SUMAAPI SumaAddUser: Payload =  %v\n", string(redactedBytes))
 	}
 
 	// Create an HTTP POST request
@@ -889,10 +887,6 @@ func SumaAddUser(sessioncookie, group, grouppassword, susemgrurl string, verbose
 // SumaRemoveUser delete a user from the suse manager
 func SumaRemoveUser(sessioncookie, group, susemgrurl string, verbose bool) (err error) {
 
-	type RemoveUser struct {
-		Login string `json:"login"`
-	}
-
 	if verbose {
 		log.Println("DEBUG SUMAAPI SumaRemoveUser: Enter function")
 		log.Println("DEBUG SUMAAPI SumaRemoveUser: ==============")
@@ -906,6 +900,22 @@ func SumaRemoveUser(sessioncookie, group, susemgrurl string, verbose bool) (err
 		return err
 	}
 
+	return SumaDeleteUser(sessioncookie, group, susemgrurl, verbose)
+}
+
+// SumaDeleteUser removes only the user from the suse manager, the system group of the user is kept.
+func SumaDeleteUser(sessioncookie, group, susemgrurl string, verbose bool) (err error) {
+
+	type RemoveUser struct {
+		Login string `json:"login"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaDeleteUser: Enter function")
+		log.Println("DEBUG SUMAAPI SumaDeleteUser: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaDeleteUser: Leave function")
+	}
+
 	//check if user exists
 	ok := sumaCheckUser(sessioncookie, group, susemgrurl, verbose)
 
@@ -917,12 +927,12 @@ func SumaRemoveUser(sessioncookie, group, susemgrurl string, verbose bool) (err
 	// Define the API endpoint
 	apiURL := fmt.Sprintf("%s%s", susemgrurl, "/rhn/manager/api")
 	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaRemoveUser: apiURL =  %s\n", apiURL)
+		log.Printf("DEBUG SUMAAPI SumaDeleteUser: apiURL =  %s\n", apiURL)
 	}
 
 	apiUserRemove := fmt.Sprintf("%s%s", apiURL, "/user/delete")
 	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaRemoveUser: apiMethod = %s\n", apiUserRemove)
+		log.Printf("DEBUG SUMAAPI SumaDeleteUser: apiMethod = %s\n", apiUserRemove)
 	}
 
 	// Create the authentication request payload
@@ -938,7 +948,7 @@ func SumaRemoveUser(sessioncookie, group, susemgrurl string, verbose bool) (err
 	}
 
 	if verbose {
-		log.Printf("DEBUG SUMAAPI SumaRemoveUser: Payload =  %v\n", string(payloadBytes))
+		log.Printf("DEBUG SUMAAPI SumaDeleteUser: Payload =  %v\n", string(payloadBytes))
 	}
 
 	// Create an HTTP POST request
@@ -967,7 +977,7 @@ func SumaRemoveUser(sessioncookie, group, susemgrurl string, verbose bool) (err
 	}()
 
 	if verbose {
-		log.Printf("DEBUG SUMAAPI: SumaRemoveUser: %v\n", resp)
+		log.Printf("DEBUG SUMAAPI: SumaDeleteUser: %v\n", resp)
 	}
 
 	if err != nil {
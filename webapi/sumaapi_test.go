@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -187,7 +188,7 @@ func TestSumaDeleteSystem_InvalidNetwork(t *testing.T) {
 		sumaGetSystemIP = oldGetSystemIP
 	}()
 
-	status, err := SumaDeleteSystem("cookie", "http://dummy", "host", "192.168.1.0", false)
+	status, _, err := SumaDeleteSystem("cookie", "http://dummy", "host", "192.168.1.0", CleanupFailOnError, false)
 	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
 		t.Errorf("expected network error, got %v", err)
 	}
@@ -196,6 +197,106 @@ func TestSumaDeleteSystem_InvalidNetwork(t *testing.T) {
 	}
 }
 
+func TestSumaDeleteSystem_ForceFallback(t *testing.T) {
+	oldGetSystemID := sumaGetSystemID
+	oldGetSystemIP := sumaGetSystemIP
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		return 42, nil
+	}
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		return "192.168.1.10", nil
+	}
+	defer func() {
+		sumaGetSystemID = oldGetSystemID
+		sumaGetSystemIP = oldGetSystemIP
+	}()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	var cleanupTypes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			CleanupType string `json:"cleanupType"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		cleanupTypes = append(cleanupTypes, payload.CleanupType)
+		if payload.CleanupType == CleanupFailOnError {
+			// the message is not interpreted, any refused delete falls back
+			fmt.Fprint(w, `{"success": false, "message": "minion host.example.com did not respond"}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "result": 1}`)
+	}))
+	defer server.Close()
+
+	status, forced, err := SumaDeleteSystem("cookie", server.URL, "host", "192.168.1.0", CleanupForce, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("expected status 200, got %d", status)
+	}
+	if !forced {
+		t.Error("expected fallback to force delete to be reported")
+	}
+	if len(cleanupTypes) != 2 || cleanupTypes[0] != CleanupFailOnError || cleanupTypes[1] != CleanupForce {
+		t.Errorf("unexpected cleanup types sent: %v", cleanupTypes)
+	}
+
+	// without cleanup error, no fallback is reported
+	cleanupTypes = nil
+	_, forced, err = SumaDeleteSystem("cookie", server.URL, "host", "192.168.1.0", CleanupNone, false)
+	if err != nil || forced {
+		t.Errorf("expected delete without fallback, got forced=%v err=%v", forced, err)
+	}
+}
+
+func TestSumaDeleteSystem_ForceNoFallback(t *testing.T) {
+	oldGetSystemID := sumaGetSystemID
+	oldGetSystemIP := sumaGetSystemIP
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		return 42, nil
+	}
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		return "192.168.1.10", nil
+	}
+	defer func() {
+		sumaGetSystemID = oldGetSystemID
+		sumaGetSystemIP = oldGetSystemIP
+	}()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+	}{
+		{"HTTP 500", func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) }},
+		{"HTTP 401", func(w http.ResponseWriter) { w.WriteHeader(http.StatusUnauthorized) }},
+		{"invalid response", func(w http.ResponseWriter) { fmt.Fprint(w, `<html>maintenance</html>`) }},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			tt.handler(w)
+		}))
+
+		status, forced, err := SumaDeleteSystem("cookie", server.URL, "host", "192.168.1.0", CleanupForce, false)
+		server.Close()
+
+		if err == nil || forced || status != -1 {
+			t.Errorf("%s: expected error without fallback, got status=%d forced=%v err=%v", tt.name, status, forced, err)
+		}
+		if calls != 1 {
+			t.Errorf("%s: expected one delete request, got %d", tt.name, calls)
+		}
+	}
+}
+
 func TestSumaAddUser_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -228,6 +329,35 @@ func TestSumaAddUser_Success(t *testing.T) {
 	}
 }
 
+func TestSumaAddUser_VerboseRedactsPassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/user/listUsers":
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/user/create":
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+
+	if _, err := SumaAddUser("dummy", "testuser", "s3cret-generated", server.URL, true); err != nil {
+		t.Fatalf("SumaAddUser failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cret-generated") {
+		t.Error("the password must not be logged")
+	}
+	if !strings.Contains(buf.String(), "Payload") {
+		t.Errorf("expected the redacted payload to be logged, got %q", buf.String())
+	}
+}
+
 func TestSumaAddUser_Failure(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -331,6 +461,38 @@ func TestSumaRemoveUser_UserDoesNotExist(t *testing.T) {
 	}
 }
 
+// Test SumaDeleteUser keeps the system group of the user
+func TestSumaDeleteUser_KeepsSystemGroup(t *testing.T) {
+	defer restoreDeps()
+
+	sumaRemoveSystemGroup = func(sessioncookie, susemgrurl, group string, verbose bool) (int, error) {
+		t.Errorf("system group %s must not be removed", group)
+		return http.StatusOK, nil
+	}
+	sumaCheckUser = func(sessioncookie, group, susemgrurl string, verbose bool) bool {
+		return true
+	}
+
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rhn/manager/api/user/delete" {
+			deleted = true
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		t.Errorf("unexpected path: %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	err := SumaDeleteUser("testcookie", "testuser", server.URL, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !deleted {
+		t.Error("expected the user to be deleted")
+	}
+}
+
 // suppressStderr redirects os.Stderr to a pipe and drains it in a goroutine.
 // It returns a restore function to be deferred.
 func suppressStderr(t *testing.T) func() {
@@ -0,0 +1,188 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChannelDeviationType describes a system, which is not subscribed to the configured software channels.
+type ChannelDeviationType struct {
+	System        string   `json:"system"`
+	BaseChannel   string   `json:"base_channel"`
+	ChildChannels []string `json:"child_channels"`
+	WantBase      string   `json:"want_base_channel"`
+	WantChildren  []string `json:"want_child_channels"`
+}
+
+func sameChannels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa := append([]string{}, a...)
+	sb := append([]string{}, b...)
+	sort.Strings(sa)
+	sort.Strings(sb)
+	return strings.Join(sa, ",") == strings.Join(sb, ",")
+}
+
+var sumaListBaseChildren = func(sessioncookie, susemgr, basechannel string, verbose bool) (labels []string, err error) {
+
+	type ResultChannel struct {
+		Label string `json:"label"`
+	}
+
+	var rsp []ResultChannel
+	err = sumaGet(sessioncookie, susemgr, "/channel/software/listChildren", url.Values{"channelLabel": {basechannel}}, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range rsp {
+		labels = append(labels, c.Label)
+	}
+
+	return labels, nil
+}
+
+// sumaCheckChannels compares the subscribed channels of the system with the configured channels. An empty
+// basechannel or an empty list of childchannels is not enforced. If the base channel changes, configured child
+// channels must be children of the new base channel and of the subscribed child channels only the children of the
+// new base channel are kept.
+func sumaCheckChannels(sessioncookie, susemgr string, system SystemType, basechannel string, childchannels []string, verbose bool) (deviation *ChannelDeviationType, err error) {
+
+	currentbase, err := sumaGetBaseChannel(sessioncookie, susemgr, system.ID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get base channel of %s: %v", system.Name, err)
+	}
+
+	currentchildren, err := sumaListChildChannels(sessioncookie, susemgr, system.ID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get child channels of %s: %v", system.Name, err)
+	}
+
+	d := ChannelDeviationType{
+		System:        system.Name,
+		BaseChannel:   currentbase,
+		ChildChannels: currentchildren,
+		WantBase:      basechannel,
+		WantChildren:  childchannels,
+	}
+
+	if basechannel == "" {
+		d.WantBase = currentbase
+	}
+	if len(childchannels) == 0 {
+		d.WantChildren = currentchildren
+	}
+
+	if d.WantBase != currentbase {
+		valid, err := sumaListBaseChildren(sessioncookie, susemgr, d.WantBase, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("could not get child channels of %s: %v", d.WantBase, err)
+		}
+		isValid := make(map[string]bool)
+		for _, label := range valid {
+			isValid[label] = true
+		}
+
+		var children []string
+		for _, label := range d.WantChildren {
+			switch {
+			case isValid[label]:
+				children = append(children, label)
+			case len(childchannels) > 0:
+				return nil, fmt.Errorf("child channel %s of %s is no child of base channel %s", label, system.Name, d.WantBase)
+			default:
+				log.Printf("child channel %s of %s is dropped, it is no child of base channel %s\n", label, system.Name, d.WantBase)
+			}
+		}
+		d.WantChildren = children
+	}
+
+	if d.WantBase == currentbase && sameChannels(d.WantChildren, currentchildren) {
+		return nil, nil
+	}
+
+	return &d, nil
+}
+
+// SumaGetChannelDeviations returns the systems, which are not subscribed to the configured channels.
+func SumaGetChannelDeviations(sessioncookie, susemgr string, systems []SystemType, basechannel string, childchannels []string, verbose bool) (deviations []ChannelDeviationType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaGetChannelDeviations: Enter function")
+		log.Println("DEBUG SUMAAPI SumaGetChannelDeviations: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaGetChannelDeviations: Leave function")
+	}
+
+	for _, system := range systems {
+		d, err := sumaCheckChannels(sessioncookie, susemgr, system, basechannel, childchannels, verbose)
+		if err != nil {
+			return deviations, err
+		}
+		if d != nil {
+			deviations = append(deviations, *d)
+		}
+	}
+
+	return deviations, nil
+}
+
+// SumaEnforceChannels schedules a change of the software channels of a system, if the system is not subscribed to
+// the configured channels. The system must belong to the permitted network. If nothing is to change, the returned
+// action ID is 0.
+func SumaEnforceChannels(sessioncookie, susemgr, hostname, network, basechannel string, childchannels []string, earliest time.Time, verbose bool) (action ActionType, err error) {
+
+	type ChangeChannels struct {
+		ServerID           int      `json:"sid"`
+		BaseChannelLabel   string   `json:"baseChannelLabel"`
+		ChildLabels        []string `json:"childLabels"`
+		EarliestOccurrence string   `json:"earliestOccurrence"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaEnforceChannels: Enter function")
+		log.Println("DEBUG SUMAAPI SumaEnforceChannels: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaEnforceChannels: Leave function")
+	}
+
+	action.System = hostname
+
+	id, ip, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return action, err
+	}
+
+	d, err := sumaCheckChannels(sessioncookie, susemgr, SystemType{ID: id, Name: hostname, IP: ip}, basechannel, childchannels, verbose)
+	if err != nil {
+		return action, err
+	}
+
+	if d == nil {
+		if verbose {
+			log.Printf("DEBUG SUMAAPI SumaEnforceChannels: channels of %s are up to date\n", hostname)
+		}
+		return action, nil
+	}
+
+	payload := ChangeChannels{
+		ServerID:           id,
+		BaseChannelLabel:   d.WantBase,
+		ChildLabels:        d.WantChildren,
+		EarliestOccurrence: sumaTime(earliest),
+	}
+	if payload.ChildLabels == nil {
+		payload.ChildLabels = []string{}
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/system/scheduleChangeChannels", payload, &action.ActionID, verbose)
+	if err != nil {
+		return action, fmt.Errorf("could not schedule channel change for %s: %v", hostname, err)
+	}
+
+	return action, nil
+}
@@ -0,0 +1,104 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newChannelServer(t *testing.T, changed *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/getSubscribedBaseChannel":
+			fmt.Fprint(w, `{"success": true, "result": {"label": "sles15-sp5-pool"}}`)
+		case "/rhn/manager/api/system/listSubscribedChildChannels":
+			fmt.Fprint(w, `{"success": true, "result": [{"label": "sles15-sp5-updates"}]}`)
+		case "/rhn/manager/api/channel/software/listChildren":
+			switch r.URL.Query().Get("channelLabel") {
+			case "sles15-sp6-pool":
+				fmt.Fprint(w, `{"success": true, "result": [{"label": "sles15-sp6-updates"}]}`)
+			default:
+				fmt.Fprint(w, `{"success": true, "result": [{"label": "sles15-sp5-updates"}]}`)
+			}
+		case "/rhn/manager/api/system/scheduleChangeChannels":
+			var payload struct {
+				BaseChannelLabel string   `json:"baseChannelLabel"`
+				ChildLabels      []string `json:"childLabels"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*changed = append([]string{payload.BaseChannelLabel}, payload.ChildLabels...)
+			fmt.Fprint(w, `{"success": true, "result": 700}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestSumaGetChannelDeviations(t *testing.T) {
+	var changed []string
+	server := newChannelServer(t, &changed)
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}}
+
+	deviations, err := SumaGetChannelDeviations("cookie", server.URL, systems, "sles15-sp5-pool", []string{"sles15-sp5-updates"}, false)
+	if err != nil || len(deviations) != 0 {
+		t.Errorf("expected no deviations, got %+v, %v", deviations, err)
+	}
+
+	// the base channel is kept, if it is not configured
+	deviations, err = SumaGetChannelDeviations("cookie", server.URL, systems, "", []string{"sles15-sp5-updates", "sle-module-basesystem15-sp5-updates"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deviations) != 1 || deviations[0].WantBase != "sles15-sp5-pool" || len(deviations[0].WantChildren) != 2 {
+		t.Errorf("unexpected deviations: %+v", deviations)
+	}
+	if changed != nil {
+		t.Errorf("channels must not be changed in check mode, got %v", changed)
+	}
+}
+
+func TestSumaEnforceChannels(t *testing.T) {
+	patchSystemLookup(t, 42, "192.168.1.10")
+
+	var changed []string
+	server := newChannelServer(t, &changed)
+	defer server.Close()
+
+	action, err := SumaEnforceChannels("cookie", server.URL, "host", "192.168.1.0", "sles15-sp6-pool", nil, time.Time{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action.ActionID != 700 {
+		t.Errorf("expected action 700, got %d", action.ActionID)
+	}
+	// the child channel of the old base channel is not valid under the new base channel
+	if len(changed) != 1 || changed[0] != "sles15-sp6-pool" {
+		t.Errorf("unexpected channel change: %v", changed)
+	}
+
+	changed = nil
+	_, err = SumaEnforceChannels("cookie", server.URL, "host", "192.168.1.0", "sles15-sp6-pool", []string{"sles15-sp6-updates"}, time.Time{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changed) != 2 || changed[0] != "sles15-sp6-pool" || changed[1] != "sles15-sp6-updates" {
+		t.Errorf("unexpected channel change: %v", changed)
+	}
+
+	changed = nil
+	_, err = SumaEnforceChannels("cookie", server.URL, "host", "192.168.1.0", "sles15-sp6-pool", []string{"sles15-sp5-updates"}, time.Time{}, false)
+	if err == nil || changed != nil {
+		t.Errorf("expected error for a child channel of another base channel, got %v, %v", changed, err)
+	}
+
+	changed = nil
+	action, err = SumaEnforceChannels("cookie", server.URL, "host", "192.168.1.0", "sles15-sp5-pool", nil, time.Time{}, false)
+	if err != nil || action.ActionID != 0 || changed != nil {
+		t.Errorf("expected no channel change, got action %d, %v, %v", action.ActionID, changed, err)
+	}
+}
@@ -0,0 +1,102 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type configChannels struct {
+	ServerIds           []int    `json:"sids"`
+	ConfigChannelLabels []string `json:"configChannelLabels"`
+}
+
+// SumaSubscribeConfigChannels subscribes the system to the configuration channels. The channels are ranked in the
+// given order and replace the current subscriptions of the system.
+func SumaSubscribeConfigChannels(sessioncookie, susemgr, hostname, network string, channels []string, verbose bool) (err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaSubscribeConfigChannels: Enter function")
+		log.Println("DEBUG SUMAAPI SumaSubscribeConfigChannels: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaSubscribeConfigChannels: Leave function")
+	}
+
+	id, _, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return err
+	}
+
+	payload := configChannels{
+		ServerIds:           []int{id},
+		ConfigChannelLabels: channels,
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/system/config/setChannels", payload, nil, verbose)
+	if err != nil {
+		return fmt.Errorf("could not subscribe %s to the configuration channels: %v", hostname, err)
+	}
+
+	return nil
+}
+
+// SumaUnsubscribeConfigChannels removes the configuration channels from the system.
+func SumaUnsubscribeConfigChannels(sessioncookie, susemgr, hostname, network string, channels []string, verbose bool) (err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaUnsubscribeConfigChannels: Enter function")
+		log.Println("DEBUG SUMAAPI SumaUnsubscribeConfigChannels: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaUnsubscribeConfigChannels: Leave function")
+	}
+
+	id, _, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return err
+	}
+
+	payload := configChannels{
+		ServerIds:           []int{id},
+		ConfigChannelLabels: channels,
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/system/config/removeChannels", payload, nil, verbose)
+	if err != nil {
+		return fmt.Errorf("could not unsubscribe %s from the configuration channels: %v", hostname, err)
+	}
+
+	return nil
+}
+
+// SumaRemoveSystem removes a System from the SUSE Manager SystemGroup without deleting it from the SUSE Manager.
+// The system must belong to the permitted network.
+func SumaRemoveSystem(sessioncookie, susemgr, hostname, group, network string, verbose bool) (statuscode int, err error) {
+
+	type AddRemoveSystem struct {
+		SystemGroupName string `json:"systemGroupName"`
+		ServerIds       []int  `json:"serverIds"`
+		Add             bool   `json:"add"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaRemoveSystem: Enter function")
+		log.Println("DEBUG SUMAAPI SumaRemoveSystem: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaRemoveSystem: Leave function")
+	}
+
+	id, _, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return -1, err
+	}
+
+	payload := AddRemoveSystem{
+		SystemGroupName: group,
+		ServerIds:       []int{id},
+		Add:             false,
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/systemgroup/addOrRemoveSystems", payload, nil, verbose)
+	if err != nil {
+		return -1, fmt.Errorf("could not remove %s from group %s: %v", hostname, group, err)
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,72 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSumaSubscribeConfigChannels(t *testing.T) {
+	patchSystemLookup(t, 42, "192.168.1.10")
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rhn/manager/api/system/config/setChannels" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload configChannels
+		json.NewDecoder(r.Body).Decode(&payload)
+		if len(payload.ServerIds) != 1 || payload.ServerIds[0] != 42 {
+			t.Errorf("unexpected sids: %v", payload.ServerIds)
+		}
+		got = payload.ConfigChannelLabels
+		fmt.Fprint(w, `{"success": true, "result": 1}`)
+	}))
+	defer server.Close()
+
+	err := SumaSubscribeConfigChannels("cookie", server.URL, "host", "192.168.1.0", []string{"sshd", "motd", "monitoring"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "sshd,motd,monitoring" {
+		t.Errorf("expected channels in order, got %v", got)
+	}
+}
+
+func TestSumaRemoveSystem(t *testing.T) {
+	patchSystemLookup(t, 42, "192.168.1.10")
+
+	var removed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rhn/manager/api/systemgroup/addOrRemoveSystems" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload struct {
+			Add bool `json:"add"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		removed = !payload.Add
+		fmt.Fprint(w, `{"success": true, "result": 1}`)
+	}))
+	defer server.Close()
+
+	status, err := SumaRemoveSystem("cookie", server.URL, "host", "group", "192.168.1.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusOK || !removed {
+		t.Errorf("expected system to be removed, got status %d", status)
+	}
+}
+
+func TestSumaRemoveSystem_InvalidNetwork(t *testing.T) {
+	patchSystemLookup(t, 42, "10.0.0.1")
+
+	_, err := SumaRemoveSystem("cookie", "http://dummy", "host", "group", "192.168.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
+		t.Errorf("expected network error, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// RegisteredAtKey is the custom info key of the registration time of a system.
+const RegisteredAtKey = "registered_at"
+
+// ExpiresAtKey is the custom info key of the expiry time of a system.
+const ExpiresAtKey = "expires_at"
+
+// SystemInfoType describes a system of a SystemGroup with its custom info values.
+type SystemInfoType struct {
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	IP           string            `json:"ip"`
+	LastCheckin  string            `json:"last_checkin"`
+	CustomValues map[string]string `json:"custom_values"`
+}
+
+var sumaListCustomInfoKeys = func(sessioncookie, susemgr string, verbose bool) (keys map[string]bool, err error) {
+
+	type ResultCustomInfoKey struct {
+		Label       string `json:"label"`
+		Description string `json:"description"`
+	}
+
+	var rsp []ResultCustomInfoKey
+	err = sumaGet(sessioncookie, susemgr, "/system/custominfo/listAllKeys", nil, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	keys = make(map[string]bool)
+	for _, k := range rsp {
+		keys[k.Label] = true
+	}
+
+	return keys, nil
+}
+
+var sumaCreateCustomInfoKey = func(sessioncookie, susemgr, key string, verbose bool) (err error) {
+
+	type CreateKey struct {
+		KeyLabel       string `json:"keyLabel"`
+		KeyDescription string `json:"keyDescription"`
+	}
+
+	payload := CreateKey{
+		KeyLabel:       key,
+		KeyDescription: fmt.Sprintf("%s of the system", key),
+	}
+
+	return sumaPost(sessioncookie, susemgr, "/system/custominfo/createKey", payload, nil, verbose)
+}
+
+var sumaGetCustomValues = func(sessioncookie, susemgr string, id int, verbose bool) (values map[string]string, err error) {
+
+	err = sumaGet(sessioncookie, susemgr, "/system/getCustomValues", sidQuery(id), &values, verbose)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+
+	return values, nil
+}
+
+// SumaSetCustomValues sets the custom info values of a system. Missing custom info keys are created. The system must
+// belong to the permitted network.
+func SumaSetCustomValues(sessioncookie, susemgr, hostname, network string, values map[string]string, verbose bool) (err error) {
+
+	type SetCustomValues struct {
+		ServerID int               `json:"sid"`
+		Values   map[string]string `json:"values"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaSetCustomValues: Enter function")
+		log.Println("DEBUG SUMAAPI SumaSetCustomValues: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaSetCustomValues: Leave function")
+	}
+
+	id, _, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return err
+	}
+
+	keys, err := sumaListCustomInfoKeys(sessioncookie, susemgr, verbose)
+	if err != nil {
+		return fmt.Errorf("could not list custom info keys: %v", err)
+	}
+
+	labels := make([]string, 0, len(values))
+	for key := range values {
+		labels = append(labels, key)
+	}
+	sort.Strings(labels)
+
+	for _, key := range labels {
+		if keys[key] {
+			continue
+		}
+		if verbose {
+			log.Printf("DEBUG SUMAAPI SumaSetCustomValues: create custom info key %s\n", key)
+		}
+		err = sumaCreateCustomInfoKey(sessioncookie, susemgr, key, verbose)
+		if err != nil {
+			return fmt.Errorf("could not create custom info key %s: %v", key, err)
+		}
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/system/setCustomValues", SetCustomValues{ServerID: id, Values: values}, nil, verbose)
+	if err != nil {
+		return fmt.Errorf("could not set custom values of %s: %v", hostname, err)
+	}
+
+	return nil
+}
+
+// SumaListSystemInfo get the last checkin and the custom info values of the systems.
+func SumaListSystemInfo(sessioncookie, susemgr string, systems []SystemType, verbose bool) (infos []SystemInfoType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaListSystemInfo: Enter function")
+		log.Println("DEBUG SUMAAPI SumaListSystemInfo: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaListSystemInfo: Leave function")
+	}
+
+	for _, system := range systems {
+		info := SystemInfoType{ID: system.ID, Name: system.Name, IP: system.IP}
+
+		info.LastCheckin, err = sumaGetLastCheckin(sessioncookie, susemgr, system.ID, verbose)
+		if err != nil {
+			return infos, fmt.Errorf("could not get last checkin of %s: %v", system.Name, err)
+		}
+
+		info.CustomValues, err = sumaGetCustomValues(sessioncookie, susemgr, system.ID, verbose)
+		if err != nil {
+			return infos, fmt.Errorf("could not get custom values of %s: %v", system.Name, err)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// ParseCustomTime parses a time of a custom info value in RFC3339 format or as date 2006-01-02.
+func ParseCustomTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is neither in RFC3339 format nor a date 2006-01-02", value)
+	}
+	return t, nil
+}
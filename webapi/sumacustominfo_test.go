@@ -0,0 +1,102 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSumaSetCustomValues(t *testing.T) {
+	patchSystemLookup(t, 42, "192.168.1.10")
+
+	var created []string
+	var values map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/custominfo/listAllKeys":
+			fmt.Fprint(w, `{"success": true, "result": [{"label": "owner", "description": "owner of the system"}]}`)
+		case "/rhn/manager/api/system/custominfo/createKey":
+			var payload struct {
+				KeyLabel string `json:"keyLabel"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			created = append(created, payload.KeyLabel)
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		case "/rhn/manager/api/system/setCustomValues":
+			var payload struct {
+				ServerID int               `json:"sid"`
+				Values   map[string]string `json:"values"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.ServerID != 42 {
+				t.Errorf("unexpected sid: %d", payload.ServerID)
+			}
+			values = payload.Values
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	meta := map[string]string{"owner": "alice", "ticket": "INC-1", RegisteredAtKey: "2026-10-18T10:00:00Z"}
+
+	err := SumaSetCustomValues("cookie", server.URL, "host", "192.168.1.0", meta, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(created, ",") != "registered_at,ticket" {
+		t.Errorf("expected missing keys to be created, got %v", created)
+	}
+	if len(values) != 3 || values["owner"] != "alice" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestSumaSetCustomValues_InvalidNetwork(t *testing.T) {
+	patchSystemLookup(t, 42, "10.0.0.1")
+
+	err := SumaSetCustomValues("cookie", "http://dummy", "host", "192.168.1.0", map[string]string{"owner": "alice"}, false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
+		t.Errorf("expected network error, got %v", err)
+	}
+}
+
+func TestSumaListSystemInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/getName":
+			fmt.Fprint(w, `{"success": true, "result": {"id": 1, "name": "a.example.com", "last_checkin": "2026-10-01T10:00:00Z"}}`)
+		case "/rhn/manager/api/system/getCustomValues":
+			fmt.Fprint(w, `{"success": true, "result": {"expires_at": "2026-11-01"}}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com", IP: "192.168.1.10"}}
+
+	infos, err := SumaListSystemInfo("cookie", server.URL, systems, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(infos) != 1 || infos[0].LastCheckin != "2026-10-01T10:00:00Z" || infos[0].CustomValues[ExpiresAtKey] != "2026-11-01" {
+		t.Errorf("unexpected infos: %+v", infos)
+	}
+}
+
+func TestParseCustomTime(t *testing.T) {
+	if got, err := ParseCustomTime("2026-11-01T12:00:00+02:00"); err != nil || got.UTC().Hour() != 10 {
+		t.Errorf("unexpected RFC3339 result: %v %v", got, err)
+	}
+	if got, err := ParseCustomTime("2026-11-01"); err != nil || got.Day() != 1 || got.Month() != 11 {
+		t.Errorf("unexpected date result: %v %v", got, err)
+	}
+	if _, err := ParseCustomTime("next week"); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
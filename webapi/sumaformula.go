@@ -0,0 +1,125 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+)
+
+// FormulaType is a Salt formula with the pillar data of the group.
+type FormulaType struct {
+	Name string                 `json:"name"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+var sumaListGroupFormulas = func(sessioncookie, susemgr string, groupID int, verbose bool) (names []string, err error) {
+
+	err = sumaGet(sessioncookie, susemgr, "/formula/getFormulasByGroupId", url.Values{"systemGroupId": {strconv.Itoa(groupID)}}, &names, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+var sumaGetGroupFormulaData = func(sessioncookie, susemgr string, groupID int, formula string, verbose bool) (data map[string]interface{}, err error) {
+
+	query := url.Values{
+		"groupId":     {strconv.Itoa(groupID)},
+		"formulaName": {formula},
+	}
+
+	err = sumaGet(sessioncookie, susemgr, "/formula/getGroupFormulaData", query, &data, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// SumaSetGroupFormulas assigns the formulas to the SystemGroup and sets the pillar data of the formulas. Formulas
+// of the group, which are not in the list, are removed from the group.
+func SumaSetGroupFormulas(sessioncookie, susemgr, group string, formulas []FormulaType, verbose bool) (err error) {
+
+	type SetFormulas struct {
+		SystemGroupID int      `json:"systemGroupId"`
+		FormulaNames  []string `json:"formulaNames"`
+	}
+
+	type SetFormulaData struct {
+		SystemGroupID int                    `json:"systemGroupId"`
+		FormulaName   string                 `json:"formulaName"`
+		Content       map[string]interface{} `json:"content"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaSetGroupFormulas: Enter function")
+		log.Println("DEBUG SUMAAPI SumaSetGroupFormulas: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaSetGroupFormulas: Leave function")
+	}
+
+	groupID, err := sumaGetSystemGroupID(sessioncookie, susemgr, group, verbose)
+	if err != nil {
+		return fmt.Errorf("could not get systemgroup %s: %v", group, err)
+	}
+
+	names := []string{}
+	for _, f := range formulas {
+		names = append(names, f.Name)
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/formula/setFormulasOfGroup", SetFormulas{SystemGroupID: groupID, FormulaNames: names}, nil, verbose)
+	if err != nil {
+		return fmt.Errorf("could not assign formulas to group %s: %v", group, err)
+	}
+
+	for _, f := range formulas {
+		if f.Data == nil {
+			continue
+		}
+
+		payload := SetFormulaData{
+			SystemGroupID: groupID,
+			FormulaName:   f.Name,
+			Content:       f.Data,
+		}
+
+		err = sumaPost(sessioncookie, susemgr, "/formula/setGroupFormulaData", payload, nil, verbose)
+		if err != nil {
+			return fmt.Errorf("could not set data of formula %s for group %s: %v", f.Name, group, err)
+		}
+	}
+
+	return nil
+}
+
+// SumaGetGroupFormulas returns the formulas of the SystemGroup with their pillar data.
+func SumaGetGroupFormulas(sessioncookie, susemgr, group string, verbose bool) (formulas []FormulaType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaGetGroupFormulas: Enter function")
+		log.Println("DEBUG SUMAAPI SumaGetGroupFormulas: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaGetGroupFormulas: Leave function")
+	}
+
+	groupID, err := sumaGetSystemGroupID(sessioncookie, susemgr, group, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get systemgroup %s: %v", group, err)
+	}
+
+	names, err := sumaListGroupFormulas(sessioncookie, susemgr, groupID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get formulas of group %s: %v", group, err)
+	}
+
+	for _, name := range names {
+		data, err := sumaGetGroupFormulaData(sessioncookie, susemgr, groupID, name, verbose)
+		if err != nil {
+			return formulas, fmt.Errorf("could not get data of formula %s for group %s: %v", name, group, err)
+		}
+		formulas = append(formulas, FormulaType{Name: name, Data: data})
+	}
+
+	return formulas, nil
+}
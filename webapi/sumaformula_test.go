@@ -0,0 +1,89 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSumaSetGroupFormulas(t *testing.T) {
+	var assigned []string
+	data := make(map[string]map[string]interface{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/systemgroup/getDetails":
+			fmt.Fprint(w, `{"success": true, "result": {"id": 7, "name": "testgroup"}}`)
+		case "/rhn/manager/api/formula/setFormulasOfGroup":
+			var payload struct {
+				SystemGroupID int      `json:"systemGroupId"`
+				FormulaNames  []string `json:"formulaNames"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.SystemGroupID != 7 {
+				t.Errorf("unexpected systemGroupId: %d", payload.SystemGroupID)
+			}
+			assigned = payload.FormulaNames
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		case "/rhn/manager/api/formula/setGroupFormulaData":
+			var payload struct {
+				FormulaName string                 `json:"formulaName"`
+				Content     map[string]interface{} `json:"content"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			data[payload.FormulaName] = payload.Content
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	formulas := []FormulaType{
+		{Name: "locale", Data: map[string]interface{}{"timezone": "Europe/Berlin"}},
+		{Name: "prometheus-exporters"},
+	}
+
+	err := SumaSetGroupFormulas("cookie", server.URL, "testgroup", formulas, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assigned) != 2 || assigned[0] != "locale" || assigned[1] != "prometheus-exporters" {
+		t.Errorf("unexpected formulas: %v", assigned)
+	}
+	if len(data) != 1 || data["locale"]["timezone"] != "Europe/Berlin" {
+		t.Errorf("unexpected formula data: %v", data)
+	}
+}
+
+func TestSumaGetGroupFormulas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/systemgroup/getDetails":
+			fmt.Fprint(w, `{"success": true, "result": {"id": 7, "name": "testgroup"}}`)
+		case "/rhn/manager/api/formula/getFormulasByGroupId":
+			if r.URL.Query().Get("systemGroupId") != "7" {
+				t.Errorf("unexpected systemGroupId: %s", r.URL.Query().Get("systemGroupId"))
+			}
+			fmt.Fprint(w, `{"success": true, "result": ["locale"]}`)
+		case "/rhn/manager/api/formula/getGroupFormulaData":
+			if r.URL.Query().Get("formulaName") != "locale" {
+				t.Errorf("unexpected formulaName: %s", r.URL.Query().Get("formulaName"))
+			}
+			fmt.Fprint(w, `{"success": true, "result": {"timezone": "Europe/Berlin"}}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	formulas, err := SumaGetGroupFormulas("cookie", server.URL, "testgroup", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(formulas) != 1 || formulas[0].Name != "locale" || formulas[0].Data["timezone"] != "Europe/Berlin" {
+		t.Errorf("unexpected formulas: %+v", formulas)
+	}
+}
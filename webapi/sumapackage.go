@@ -0,0 +1,168 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+var sumaListChannelPackages = func(sessioncookie, susemgr, label string, verbose bool) (packages map[string]int, err error) {
+
+	type ResultPackage struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}
+
+	var rsp []ResultPackage
+	err = sumaGet(sessioncookie, susemgr, "/channel/software/listLatestPackages", url.Values{"channelLabel": {label}}, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	packages = make(map[string]int)
+	for _, p := range rsp {
+		if _, exists := packages[p.Name]; !exists {
+			packages[p.Name] = p.ID
+		}
+	}
+
+	return packages, nil
+}
+
+var sumaListInstalledPackages = func(sessioncookie, susemgr string, id int, verbose bool) (packages map[string]int, err error) {
+
+	type ResultPackage struct {
+		Name      string `json:"name"`
+		PackageID int    `json:"package_id"`
+	}
+
+	var rsp []ResultPackage
+	err = sumaGet(sessioncookie, susemgr, "/system/listInstalledPackages", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	packages = make(map[string]int)
+	for _, p := range rsp {
+		if _, exists := packages[p.Name]; !exists && p.PackageID != 0 {
+			packages[p.Name] = p.PackageID
+		}
+	}
+
+	return packages, nil
+}
+
+// sumaResolveInstalledPackages resolves the package names to the IDs of the installed packages of the system.
+func sumaResolveInstalledPackages(sessioncookie, susemgr string, system SystemType, names []string, verbose bool) (ids []int, err error) {
+
+	installed, err := sumaListInstalledPackages(sessioncookie, susemgr, system.ID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not list installed packages of %s: %v", system.Name, err)
+	}
+
+	for _, name := range names {
+		id, exists := installed[name]
+		if !exists {
+			return nil, fmt.Errorf("package %s not found in the installed packages of %s", name, system.Name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// sumaResolvePackages resolves the package names to the IDs of the latest packages in the subscribed channels of the system.
+func sumaResolvePackages(sessioncookie, susemgr string, system SystemType, names []string, verbose bool) (ids []int, err error) {
+
+	basechannel, err := sumaGetBaseChannel(sessioncookie, susemgr, system.ID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get base channel of %s: %v", system.Name, err)
+	}
+
+	childchannels, err := sumaListChildChannels(sessioncookie, susemgr, system.ID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not get child channels of %s: %v", system.Name, err)
+	}
+
+	found := make(map[string]int)
+	for _, label := range append([]string{basechannel}, childchannels...) {
+		if label == "" {
+			continue
+		}
+		packages, err := sumaListChannelPackages(sessioncookie, susemgr, label, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("could not list packages of channel %s: %v", label, err)
+		}
+		for name, id := range packages {
+			if _, exists := found[name]; !exists {
+				found[name] = id
+			}
+		}
+	}
+
+	for _, name := range names {
+		id, exists := found[name]
+		if !exists {
+			return nil, fmt.Errorf("package %s not found in the channels of %s", name, system.Name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// SumaSchedulePackages schedules the installation or with remove the removal of the packages on the systems.
+// The package names are resolved in the subscribed channels of every system, for a removal in the installed
+// packages of every system.
+func SumaSchedulePackages(sessioncookie, susemgr string, systems []SystemType, packages []string, remove bool, earliest time.Time, verbose bool) (actions []ActionType, err error) {
+
+	type SchedulePackages struct {
+		ServerID           int    `json:"sid"`
+		PackageIds         []int  `json:"packageIds"`
+		EarliestOccurrence string `json:"earliestOccurrence"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaSchedulePackages: Enter function")
+		log.Println("DEBUG SUMAAPI SumaSchedulePackages: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaSchedulePackages: Leave function")
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no packages given")
+	}
+
+	apimethod := "/system/schedulePackageInstall"
+	if remove {
+		apimethod = "/system/schedulePackageRemove"
+	}
+
+	for _, system := range systems {
+		var ids []int
+		if remove {
+			ids, err = sumaResolveInstalledPackages(sessioncookie, susemgr, system, packages, verbose)
+		} else {
+			ids, err = sumaResolvePackages(sessioncookie, susemgr, system, packages, verbose)
+		}
+		if err != nil {
+			return actions, err
+		}
+
+		payload := SchedulePackages{
+			ServerID:           system.ID,
+			PackageIds:         ids,
+			EarliestOccurrence: sumaTime(earliest),
+		}
+
+		var actionID int
+		err = sumaPost(sessioncookie, susemgr, apimethod, payload, &actionID, verbose)
+		if err != nil {
+			return actions, fmt.Errorf("could not schedule packages for %s: %v", system.Name, err)
+		}
+
+		actions = append(actions, ActionType{System: system.Name, ActionID: actionID})
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,91 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPackageServer(t *testing.T, apimethod string, scheduled *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/getSubscribedBaseChannel":
+			fmt.Fprint(w, `{"success": true, "result": {"label": "base"}}`)
+		case "/rhn/manager/api/system/listSubscribedChildChannels":
+			fmt.Fprint(w, `{"success": true, "result": [{"label": "child"}]}`)
+		case "/rhn/manager/api/channel/software/listLatestPackages":
+			if r.URL.Query().Get("channelLabel") == "base" {
+				fmt.Fprint(w, `{"success": true, "result": [{"name": "vim", "id": 11}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "result": [{"name": "strace", "id": 22}]}`)
+		case "/rhn/manager/api/system/listInstalledPackages":
+			// the installed vim is older than the latest vim of the channel
+			fmt.Fprint(w, `{"success": true, "result": [{"name": "vim", "version": "9.0", "package_id": 7}, {"name": "strace", "package_id": 22}]}`)
+		case "/rhn/manager/api" + apimethod:
+			var payload struct {
+				PackageIds []int `json:"packageIds"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*scheduled = payload.PackageIds
+			fmt.Fprint(w, `{"success": true, "result": 600}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestSumaSchedulePackages_Install(t *testing.T) {
+	var scheduled []int
+	server := newPackageServer(t, "/system/schedulePackageInstall", &scheduled)
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}}
+
+	actions, err := SumaSchedulePackages("cookie", server.URL, systems, []string{"vim", "strace"}, false, time.Time{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(actions) != 1 || actions[0].ActionID != 600 {
+		t.Errorf("unexpected actions: %+v", actions)
+	}
+	if len(scheduled) != 2 || scheduled[0] != 11 || scheduled[1] != 22 {
+		t.Errorf("unexpected package IDs: %v", scheduled)
+	}
+}
+
+func TestSumaSchedulePackages_RemoveUnknownPackage(t *testing.T) {
+	var scheduled []int
+	server := newPackageServer(t, "/system/schedulePackageRemove", &scheduled)
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}}
+
+	_, err := SumaSchedulePackages("cookie", server.URL, systems, []string{"emacs"}, true, time.Time{}, false)
+	if err == nil || !strings.Contains(err.Error(), "package emacs not found") {
+		t.Errorf("expected unknown package error, got %v", err)
+	}
+	if scheduled != nil {
+		t.Errorf("no packages must be scheduled, got %v", scheduled)
+	}
+}
+
+func TestSumaSchedulePackages_RemoveInstalledVersion(t *testing.T) {
+	var scheduled []int
+	server := newPackageServer(t, "/system/schedulePackageRemove", &scheduled)
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}}
+
+	_, err := SumaSchedulePackages("cookie", server.URL, systems, []string{"vim"}, true, time.Time{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scheduled) != 1 || scheduled[0] != 7 {
+		t.Errorf("expected the installed package 7, got %v", scheduled)
+	}
+}
@@ -0,0 +1,85 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// StaleSystemType is a system, which is past its expiry or did not check in for too long.
+type StaleSystemType struct {
+	Group       string `json:"group"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	IP          string `json:"ip"`
+	LastCheckin string `json:"last_checkin"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// staleReason returns why the system is stale or an empty string. With days 0 the last checkin is not checked. An
+// invalid expires_at or last checkin is logged. A system with a date as expires_at expires at the end of the day.
+func staleReason(info SystemInfoType, now time.Time, days int) string {
+
+	if expires := info.CustomValues[ExpiresAtKey]; expires != "" {
+		t, err := ParseCustomTime(expires)
+		if _, derr := time.Parse("2006-01-02", expires); derr == nil {
+			t = t.AddDate(0, 0, 1)
+		}
+		if err != nil {
+			log.Printf("ignore invalid %s of %s: %v\n", ExpiresAtKey, info.Name, err)
+		} else if now.After(t) {
+			return fmt.Sprintf("expired at %s", expires)
+		}
+	}
+
+	if days > 0 && info.LastCheckin != "" {
+		t, err := ParseCustomTime(info.LastCheckin)
+		if err != nil {
+			log.Printf("cannot check the last checkin of %s, the system is not reaped: %v\n", info.Name, err)
+		} else if now.Sub(t) > time.Duration(days)*24*time.Hour {
+			return fmt.Sprintf("not checked in since %s", info.LastCheckin)
+		}
+	}
+
+	return ""
+}
+
+// SumaFindStaleSystems returns the systems of the group in the permitted network, which are past their expires_at
+// custom value or, with days greater than 0, did not check in for the given number of days.
+func SumaFindStaleSystems(sessioncookie, susemgr, group, network string, now time.Time, days int, verbose bool) (stale []StaleSystemType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaFindStaleSystems: Enter function")
+		log.Println("DEBUG SUMAAPI SumaFindStaleSystems: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaFindStaleSystems: Leave function")
+	}
+
+	systems, err := SumaGetTargetSystems(sessioncookie, susemgr, nil, group, network, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := SumaListSystemInfo(sessioncookie, susemgr, systems, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		reason := staleReason(info, now, days)
+		if reason == "" {
+			continue
+		}
+		stale = append(stale, StaleSystemType{
+			Group:       group,
+			ID:          info.ID,
+			Name:        info.Name,
+			IP:          info.IP,
+			LastCheckin: info.LastCheckin,
+			ExpiresAt:   info.CustomValues[ExpiresAtKey],
+			Reason:      reason,
+		})
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,88 @@
+package webapi
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleReason(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		info SystemInfoType
+		days int
+		want string
+	}{
+		{SystemInfoType{Name: "expired", CustomValues: map[string]string{ExpiresAtKey: "2026-10-01"}}, 0, "expired at 2026-10-01"},
+		{SystemInfoType{Name: "expires today", CustomValues: map[string]string{ExpiresAtKey: "2026-10-18"}}, 0, ""},
+		{SystemInfoType{Name: "expired yesterday", CustomValues: map[string]string{ExpiresAtKey: "2026-10-17"}}, 0, "expired at 2026-10-17"},
+		{SystemInfoType{Name: "expired this morning", CustomValues: map[string]string{ExpiresAtKey: "2026-10-18T08:00:00Z"}}, 0, "expired at 2026-10-18T08:00:00Z"},
+		{SystemInfoType{Name: "valid", CustomValues: map[string]string{ExpiresAtKey: "2026-12-31"}, LastCheckin: "2026-10-18T10:00:00Z"}, 7, ""},
+		{SystemInfoType{Name: "idle", LastCheckin: "2026-10-01T10:00:00Z"}, 7, "not checked in since 2026-10-01T10:00:00Z"},
+		{SystemInfoType{Name: "idle without days", LastCheckin: "2026-10-01T10:00:00Z"}, 0, ""},
+		{SystemInfoType{Name: "invalid", CustomValues: map[string]string{ExpiresAtKey: "soon"}}, 0, ""},
+	}
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	for _, tt := range tests {
+		got := staleReason(tt.info, now, tt.days)
+		if got != tt.want {
+			t.Errorf("staleReason(%s) = %q; want %q", tt.info.Name, got, tt.want)
+		}
+	}
+}
+
+func TestStaleReason_InvalidLastCheckin(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+
+	info := SystemInfoType{Name: "garbled", LastCheckin: "yesterday"}
+	if got := staleReason(info, time.Now(), 7); got != "" {
+		t.Errorf("expected no reason, got %q", got)
+	}
+	if !strings.Contains(buf.String(), "cannot check the last checkin of garbled") {
+		t.Errorf("expected the invalid last checkin to be logged, got %q", buf.String())
+	}
+}
+
+func TestSumaFindStaleSystems(t *testing.T) {
+	patchGroupSystems(t, map[string]string{
+		"old.example.com": "192.168.1.10",
+		"out.example.com": "10.0.0.1",
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/getName":
+			fmt.Fprint(w, `{"success": true, "result": {"last_checkin": "2026-10-18T10:00:00Z"}}`)
+		case "/rhn/manager/api/system/getCustomValues":
+			fmt.Fprint(w, `{"success": true, "result": {"expires_at": "2026-10-01"}}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	stale, err := SumaFindStaleSystems("cookie", server.URL, "group", "192.168.1.0", now, 30, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stale) != 1 || stale[0].Name != "old.example.com" || stale[0].Group != "group" || !strings.HasPrefix(stale[0].Reason, "expired") {
+		t.Errorf("expected only old.example.com, got %+v", stale)
+	}
+}
@@ -0,0 +1,140 @@
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// sumaResponse is the envelope of every SUSE Manager API response.
+type sumaResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// sumaAPIError is a failure, which is reported by the SUSE Manager API in the response.
+type sumaAPIError struct {
+	apimethod string
+	message   string
+}
+
+func (e *sumaAPIError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.apimethod, e.message)
+}
+
+// sumaGet calls a SUSE Manager API method with the given query parameters and
+// unmarshals the result of the response into result.
+var sumaGet = func(sessioncookie, susemgr, apimethod string, query url.Values, result interface{}, verbose bool) error {
+
+	apiMethod := fmt.Sprintf("%s%s%s", susemgr, "/rhn/manager/api", apimethod)
+	if len(query) > 0 {
+		apiMethod = fmt.Sprintf("%s?%s", apiMethod, query.Encode())
+	}
+
+	req, err := http.NewRequest(http.MethodGet, apiMethod, nil)
+	if err != nil {
+		log.Printf("error creating request: %v\n", err)
+		return err
+	}
+
+	return sumaDo(req, sessioncookie, apimethod, result, verbose)
+}
+
+// sumaPost calls a SUSE Manager API method with payload as JSON body and
+// unmarshals the result of the response into result.
+var sumaPost = func(sessioncookie, susemgr, apimethod string, payload, result interface{}, verbose bool) error {
+
+	apiMethod := fmt.Sprintf("%s%s%s", susemgr, "/rhn/manager/api", apimethod)
+
+	// Marshal the payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshalling payload: %v\n", err)
+		return err
+	}
+
+	if verbose {
+		log.Printf("DEBUG SUMAAPI %s: Payload = %s\n", apimethod, string(payloadBytes))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, apiMethod, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		log.Printf("error creating request: %v\n", err)
+		return err
+	}
+
+	return sumaDo(req, sessioncookie, apimethod, result, verbose)
+}
+
+func sumaDo(req *http.Request, sessioncookie, apimethod string, result interface{}, verbose bool) error {
+
+	if verbose {
+		log.Printf("DEBUG SUMAAPI %s: apiMethod = %s\n", apimethod, req.URL)
+	}
+
+	// Add headers
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{
+		Name:  "pxt-session-cookie",
+		Value: sessioncookie,
+	})
+
+	// Send the HTTP request
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("error sending request: %v\n", err)
+		return err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("error closing response body: %v\n", err)
+		}
+	}()
+
+	// Read response body
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("error reading http response: %v\n", err)
+		return err
+	}
+
+	if verbose {
+		log.Printf("DEBUG SUMAAPI %s: Got resp.Body = %s\n", apimethod, string(bodyBytes))
+	}
+
+	// Check HTTP status
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: HTTP/%d", apimethod, resp.StatusCode)
+	}
+
+	// Unmarshal the JSON response into the struct
+	var rsp sumaResponse
+	err = json.Unmarshal(bodyBytes, &rsp)
+	if err != nil {
+		log.Printf("error unmarshaling JSON: %v\n", err)
+		return err
+	}
+
+	if !rsp.Success {
+		return &sumaAPIError{apimethod: apimethod, message: rsp.Message}
+	}
+
+	if result == nil || len(rsp.Result) == 0 {
+		return nil
+	}
+
+	err = json.Unmarshal(rsp.Result, result)
+	if err != nil {
+		log.Printf("error unmarshaling JSON result: %v\n", err)
+		return err
+	}
+
+	return nil
+}
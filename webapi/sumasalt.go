@@ -0,0 +1,153 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// DefaultRegistrationTimeout is the time to wait for a system to show up in
+// SUSE Manager after its salt key was accepted.
+const DefaultRegistrationTimeout = 5 * time.Minute
+
+// Patch name resolution and polling for testing
+var lookupHost = net.LookupHost
+var pollInterval = 10 * time.Second
+
+var sumaListPendingKeys = func(sessioncookie, susemgr string, verbose bool) (minions []string, err error) {
+
+	err = sumaGet(sessioncookie, susemgr, "/saltkey/pendingList", nil, &minions, verbose)
+	if err != nil {
+		log.Printf("could not list pending salt keys: %v\n", err)
+		return nil, err
+	}
+
+	return minions, nil
+}
+
+// sumaSaltKey rejects or deletes the salt key of a minion with the apimethod /saltkey/reject or /saltkey/delete.
+var sumaSaltKey = func(sessioncookie, susemgr, apimethod, minion string, verbose bool) (err error) {
+
+	type MinionKey struct {
+		MinionID string `json:"minionId"`
+	}
+
+	err = sumaPost(sessioncookie, susemgr, apimethod, MinionKey{MinionID: minion}, nil, verbose)
+	if err != nil {
+		log.Printf("could not call %s for the salt key of %s: %v\n", apimethod, minion, err)
+		return err
+	}
+
+	return nil
+}
+
+// isMinionInNetwork resolves the minion ID and checks, that every IPv4 address of the minion
+// belongs to the permitted network.
+func isMinionInNetwork(minion, network string) bool {
+
+	addrs, err := lookupHost(minion)
+	if err != nil {
+		log.Printf("could not resolve %s: %v\n", minion, err)
+		return false
+	}
+
+	found := false
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+		if !isSystemInNetwork(addr, network) {
+			return false
+		}
+		found = true
+	}
+
+	return found
+}
+
+// SumaAcceptSaltKey accepts the pending salt key of a minion, waits until the system is registered
+// and add's it to the SUSE Manager SystemGroup. SUSE Manager reports no address for a pending
+// minion, so the key is only accepted, if the minion ID resolves into the permitted network, and
+// rejected otherwise. The minion ID is chosen by the client, so the address SUSE Manager reports
+// for the registered system is checked again and a system outside the network is deleted. The key
+// of a system, which does not register within the timeout, is deleted.
+func SumaAcceptSaltKey(sessioncookie, susemgr, hostname, group, network string, timeout time.Duration, verbose bool) (statuscode int, err error) {
+
+	type AcceptKey struct {
+		MinionID string `json:"minionId"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaAcceptSaltKey: Enter function")
+		log.Println("DEBUG SUMAAPI SumaAcceptSaltKey: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaAcceptSaltKey: Leave function")
+	}
+
+	minions, err := sumaListPendingKeys(sessioncookie, susemgr, verbose)
+	if err != nil {
+		return -1, err
+	}
+
+	pending := false
+	for _, minion := range minions {
+		if minion == hostname {
+			pending = true
+		}
+	}
+
+	if !pending {
+		return -1, fmt.Errorf("no pending salt key found for %s", hostname)
+	}
+
+	if !isMinionInNetwork(hostname, network) {
+		if err := sumaSaltKey(sessioncookie, susemgr, "/saltkey/reject", hostname, verbose); err != nil {
+			return -1, fmt.Errorf("salt key of %s cannot be accepted, the system does not belong to the permitted network, rejecting the key failed: %v", hostname, err)
+		}
+		return -1, fmt.Errorf("salt key of %s rejected, the system does not belong to the permitted network", hostname)
+	}
+
+	err = sumaPost(sessioncookie, susemgr, "/saltkey/accept", AcceptKey{MinionID: hostname}, nil, verbose)
+	if err != nil {
+		log.Printf("could not accept salt key of %s: %v\n", hostname, err)
+		return -1, err
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultRegistrationTimeout
+	}
+
+	// wait for the registration of the system
+	var id int
+	deadline := time.Now().Add(timeout)
+	for {
+		if id, err = sumaGetSystemID(sessioncookie, susemgr, hostname, verbose); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			if err := sumaSaltKey(sessioncookie, susemgr, "/saltkey/delete", hostname, verbose); err != nil {
+				return -1, fmt.Errorf("%s was not registered within %v, the accepted salt key could not be deleted: %v", hostname, timeout, err)
+			}
+			return -1, fmt.Errorf("%s was not registered within %v, salt key deleted", hostname, timeout)
+		}
+		if verbose {
+			log.Printf("DEBUG SUMAAPI SumaAcceptSaltKey: waiting for registration of %s\n", hostname)
+		}
+		time.Sleep(pollInterval)
+	}
+
+	ip, err := sumaGetSystemIP(sessioncookie, susemgr, id, verbose)
+	if err != nil {
+		return -1, fmt.Errorf("could not get the address of the registered system %s: %v", hostname, err)
+	}
+
+	if ip == "" || !isSystemInNetwork(ip, network) {
+		if err := sumaDeleteSystemByID(sessioncookie, susemgr, id, CleanupForce, verbose); err != nil {
+			return -1, fmt.Errorf("%s does not belong to the permitted network and is still registered, deleting the system failed: %v", hostname, err)
+		}
+		return -1, fmt.Errorf("salt key of %s revoked, the system does not belong to the permitted network", hostname)
+	}
+
+	return SumaAddSystem(sessioncookie, susemgr, hostname, group, network, verbose)
+}
@@ -0,0 +1,185 @@
+package webapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// patchAcceptDeps patches name resolution, polling and the system lookups used by SumaAcceptSaltKey.
+// The minion ID resolves to the resolved addresses and SUSE Manager reports the reported address for
+// the registered system. deleted reports the deletion of the system.
+func patchAcceptDeps(t *testing.T, resolved []string, reported string) (deleted *bool) {
+	oldLookupHost := lookupHost
+	oldPollInterval := pollInterval
+	oldGetSystemID := sumaGetSystemID
+	oldGetSystemIP := sumaGetSystemIP
+	oldDeleteSystemByID := sumaDeleteSystemByID
+
+	deleted = new(bool)
+	lookupHost = func(host string) ([]string, error) {
+		return resolved, nil
+	}
+	pollInterval = time.Millisecond
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		return 42, nil
+	}
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		return reported, nil
+	}
+	sumaDeleteSystemByID = func(sessioncookie, susemgr string, id int, cleanupType string, verbose bool) error {
+		*deleted = true
+		return nil
+	}
+
+	t.Cleanup(func() {
+		lookupHost = oldLookupHost
+		sumaDeleteSystemByID = oldDeleteSystemByID
+		pollInterval = oldPollInterval
+		sumaGetSystemID = oldGetSystemID
+		sumaGetSystemIP = oldGetSystemIP
+	})
+
+	return deleted
+}
+
+// newSaltKeyServer records the salt key calls by their API method.
+func newSaltKeyServer(t *testing.T, calls map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/saltkey/pendingList":
+			fmt.Fprint(w, `{"success": true, "result": ["host.example.com"]}`)
+		case "/rhn/manager/api/saltkey/accept", "/rhn/manager/api/saltkey/reject", "/rhn/manager/api/saltkey/delete":
+			calls[strings.TrimPrefix(r.URL.Path, "/rhn/manager/api")] = true
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		case "/rhn/manager/api/systemgroup/addOrRemoveSystems":
+			fmt.Fprint(w, `{"success": true, "result": 1}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestSumaAcceptSaltKey_Success(t *testing.T) {
+	patchAcceptDeps(t, []string{"192.168.1.10"}, "192.168.1.10")
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	status, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", time.Second, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("expected status 200, got %d", status)
+	}
+	if !calls["/saltkey/accept"] {
+		t.Error("expected salt key to be accepted")
+	}
+}
+
+// The minion ID resolves outside the network, so the key is rejected without being accepted.
+func TestSumaAcceptSaltKey_RejectedBeforeAccept(t *testing.T) {
+	deleted := patchAcceptDeps(t, []string{"10.0.0.1"}, "10.0.0.1")
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("expected rejected key error, got %v", err)
+	}
+	if calls["/saltkey/accept"] || !calls["/saltkey/reject"] || *deleted {
+		t.Errorf("expected only the key to be rejected, got calls %v deleted %v", calls, *deleted)
+	}
+}
+
+// The minion ID resolves into the network, but SUSE Manager reports an address outside of it.
+func TestSumaAcceptSaltKey_InvalidNetwork(t *testing.T) {
+	deleted := patchAcceptDeps(t, []string{"192.168.1.10"}, "10.0.0.1")
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
+		t.Errorf("expected network error, got %v", err)
+	}
+	if !*deleted {
+		t.Error("system outside the network must be deleted again")
+	}
+}
+
+func TestSumaAcceptSaltKey_DeleteFails(t *testing.T) {
+	patchAcceptDeps(t, []string{"192.168.1.10"}, "10.0.0.1")
+	sumaDeleteSystemByID = func(sessioncookie, susemgr string, id int, cleanupType string, verbose bool) error {
+		return errors.New("internal error")
+	}
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "still registered") {
+		t.Errorf("expected still registered error, got %v", err)
+	}
+}
+
+func TestSumaAcceptSaltKey_AddressLookupFails(t *testing.T) {
+	deleted := patchAcceptDeps(t, []string{"192.168.1.10"}, "192.168.1.10")
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		return "", errors.New("timeout")
+	}
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "could not get the address") {
+		t.Errorf("expected address lookup error, got %v", err)
+	}
+	if *deleted {
+		t.Error("a failed address lookup must not delete the system")
+	}
+}
+
+func TestSumaAcceptSaltKey_RegistrationTimeout(t *testing.T) {
+	patchAcceptDeps(t, []string{"192.168.1.10"}, "192.168.1.10")
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		return -1, errors.New("not found")
+	}
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "host.example.com", "group", "192.168.1.0", 5*time.Millisecond, false)
+	if err == nil || !strings.Contains(err.Error(), "was not registered") {
+		t.Errorf("expected registration timeout, got %v", err)
+	}
+	if !calls["/saltkey/delete"] {
+		t.Error("expected the accepted salt key to be deleted")
+	}
+}
+
+func TestSumaAcceptSaltKey_NotPending(t *testing.T) {
+	patchAcceptDeps(t, []string{"192.168.1.10"}, "192.168.1.10")
+
+	calls := map[string]bool{}
+	server := newSaltKeyServer(t, calls)
+	defer server.Close()
+
+	_, err := SumaAcceptSaltKey("cookie", server.URL, "other.example.com", "group", "192.168.1.0", time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "no pending salt key") {
+		t.Errorf("expected pending key error, got %v", err)
+	}
+}
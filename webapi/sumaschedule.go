@@ -0,0 +1,390 @@
+package webapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SystemType is a system of a SUSE Manager SystemGroup.
+type SystemType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// ActionType is an action scheduled in the SUSE Manager for a system.
+type ActionType struct {
+	System   string `json:"system"`
+	ActionID int    `json:"action_id"`
+}
+
+// AdvisorySecurity is the advisory type of security patches.
+const AdvisorySecurity = "Security Advisory"
+
+// sumaTime formats the earliest occurrence of an action, a zero time means now.
+func sumaTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.Format(time.RFC3339)
+}
+
+var sumaListGroupSystems = func(sessioncookie, susemgr, group string, verbose bool) (systems []SystemType, err error) {
+
+	err = sumaGet(sessioncookie, susemgr, "/systemgroup/listSystemsMinimal", url.Values{"systemGroupName": {group}}, &systems, verbose)
+	if err != nil {
+		log.Printf("could not list systems of group %s: %v\n", group, err)
+		return nil, err
+	}
+
+	return systems, nil
+}
+
+// SumaGetTargetSystems returns the systems a task acts on. Without hostnames all systems of the group are returned.
+// Systems outside of the permitted network are skipped for the group, requested hostnames which does not belong to
+// the group or the permitted network are an error.
+func SumaGetTargetSystems(sessioncookie, susemgr string, hostnames []string, group, network string, verbose bool) (systems []SystemType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaGetTargetSystems: Enter function")
+		log.Println("DEBUG SUMAAPI SumaGetTargetSystems: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaGetTargetSystems: Leave function")
+	}
+
+	members, err := sumaListGroupSystems(sessioncookie, susemgr, group, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hostnames) == 0 {
+		for _, m := range members {
+			m.IP, err = sumaGetSystemIP(sessioncookie, susemgr, m.ID, verbose)
+			if err != nil {
+				log.Printf("skip %s, could not get ip: %v\n", m.Name, err)
+				continue
+			}
+			if !isSystemInNetwork(m.IP, network) {
+				log.Printf("skip %s, the system does not belong to the permitted network of the group\n", m.Name)
+				continue
+			}
+			systems = append(systems, m)
+		}
+		return systems, nil
+	}
+
+	for _, hostname := range hostnames {
+		id, ip, err := sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+		if err != nil {
+			return nil, err
+		}
+
+		member := false
+		for _, m := range members {
+			if m.ID == id {
+				member = true
+			}
+		}
+
+		if !member {
+			return nil, fmt.Errorf("%s does not belong to the group %s", hostname, group)
+		}
+
+		systems = append(systems, SystemType{ID: id, Name: hostname, IP: ip})
+	}
+
+	return systems, nil
+}
+
+var sumaListRelevantErrata = func(sessioncookie, susemgr string, id int, advisoryType string, verbose bool) (errataIDs []int, err error) {
+
+	type ResultErrata struct {
+		ID           int    `json:"id"`
+		AdvisoryName string `json:"advisory_name"`
+	}
+
+	var rsp []ResultErrata
+	if advisoryType == "" {
+		err = sumaGet(sessioncookie, susemgr, "/system/getRelevantErrata", sidQuery(id), &rsp, verbose)
+	} else {
+		query := url.Values{"sid": {strconv.Itoa(id)}, "advisoryType": {advisoryType}}
+		err = sumaGet(sessioncookie, susemgr, "/system/getRelevantErrataByType", query, &rsp, verbose)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range rsp {
+		errataIDs = append(errataIDs, e.ID)
+	}
+
+	return errataIDs, nil
+}
+
+// SumaScheduleApplyErrata schedules the relevant patches of the systems. With an advisoryType only patches
+// of this type are applied. Systems without relevant patches are skipped.
+func SumaScheduleApplyErrata(sessioncookie, susemgr string, systems []SystemType, advisoryType string, earliest time.Time, verbose bool) (actions []ActionType, err error) {
+
+	type ApplyErrata struct {
+		ServerID           int    `json:"sid"`
+		ErrataIds          []int  `json:"errataIds"`
+		EarliestOccurrence string `json:"earliestOccurrence"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaScheduleApplyErrata: Enter function")
+		log.Println("DEBUG SUMAAPI SumaScheduleApplyErrata: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaScheduleApplyErrata: Leave function")
+	}
+
+	for _, system := range systems {
+		errataIDs, err := sumaListRelevantErrata(sessioncookie, susemgr, system.ID, advisoryType, verbose)
+		if err != nil {
+			return actions, fmt.Errorf("could not get relevant patches of %s: %v", system.Name, err)
+		}
+
+		if len(errataIDs) == 0 {
+			log.Printf("no relevant patches for %s\n", system.Name)
+			continue
+		}
+
+		payload := ApplyErrata{
+			ServerID:           system.ID,
+			ErrataIds:          errataIDs,
+			EarliestOccurrence: sumaTime(earliest),
+		}
+
+		var actionIDs []int
+		err = sumaPost(sessioncookie, susemgr, "/system/scheduleApplyErrata", payload, &actionIDs, verbose)
+		if err != nil {
+			return actions, fmt.Errorf("could not schedule patches for %s: %v", system.Name, err)
+		}
+
+		for _, actionID := range actionIDs {
+			actions = append(actions, ActionType{System: system.Name, ActionID: actionID})
+		}
+	}
+
+	return actions, nil
+}
+
+// SumaFilterRebootRequired returns the systems, which are flagged by the SUSE Manager as reboot required.
+func SumaFilterRebootRequired(sessioncookie, susemgr string, systems []SystemType, verbose bool) (rebootsystems []SystemType, err error) {
+
+	reboot, err := sumaListSuggestedReboot(sessioncookie, susemgr, verbose)
+	if err != nil {
+		log.Printf("could not get systems requiring a reboot: %v\n", err)
+		return nil, err
+	}
+
+	for _, system := range systems {
+		if reboot[system.ID] {
+			rebootsystems = append(rebootsystems, system)
+		}
+	}
+
+	return rebootsystems, nil
+}
+
+// SumaScheduleReboot schedules a reboot of the systems. The reboot of every further system is delayed by stagger,
+// so that not all systems are down at once.
+func SumaScheduleReboot(sessioncookie, susemgr string, systems []SystemType, earliest time.Time, stagger time.Duration, verbose bool) (actions []ActionType, err error) {
+
+	type ScheduleReboot struct {
+		ServerID           int    `json:"sid"`
+		EarliestOccurrence string `json:"earliestOccurrence"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaScheduleReboot: Enter function")
+		log.Println("DEBUG SUMAAPI SumaScheduleReboot: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaScheduleReboot: Leave function")
+	}
+
+	if earliest.IsZero() {
+		earliest = time.Now()
+	}
+
+	for i, system := range systems {
+		payload := ScheduleReboot{
+			ServerID:           system.ID,
+			EarliestOccurrence: sumaTime(earliest.Add(time.Duration(i) * stagger)),
+		}
+
+		var actionID int
+		err = sumaPost(sessioncookie, susemgr, "/system/scheduleReboot", payload, &actionID, verbose)
+		if err != nil {
+			return actions, fmt.Errorf("could not schedule reboot of %s: %v", system.Name, err)
+		}
+
+		actions = append(actions, ActionType{System: system.Name, ActionID: actionID})
+	}
+
+	return actions, nil
+}
+
+func systemIDs(systems []SystemType) (ids []int) {
+	for _, system := range systems {
+		ids = append(ids, system.ID)
+	}
+	return ids
+}
+
+// SumaScheduleApplyHighstate schedules a highstate for the systems. With test the states are only
+// evaluated and not applied.
+func SumaScheduleApplyHighstate(sessioncookie, susemgr string, systems []SystemType, earliest time.Time, test bool, verbose bool) (actions []ActionType, err error) {
+
+	type ApplyHighstate struct {
+		ServerIds          []int  `json:"sids"`
+		EarliestOccurrence string `json:"earliestOccurrence"`
+		Test               bool   `json:"test"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaScheduleApplyHighstate: Enter function")
+		log.Println("DEBUG SUMAAPI SumaScheduleApplyHighstate: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaScheduleApplyHighstate: Leave function")
+	}
+
+	if len(systems) == 0 {
+		return nil, nil
+	}
+
+	payload := ApplyHighstate{
+		ServerIds:          systemIDs(systems),
+		EarliestOccurrence: sumaTime(earliest),
+		Test:               test,
+	}
+
+	var actionID int
+	err = sumaPost(sessioncookie, susemgr, "/system/scheduleApplyHighstate", payload, &actionID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not schedule highstate: %v", err)
+	}
+
+	// one action is scheduled for all systems
+	for _, system := range systems {
+		actions = append(actions, ActionType{System: system.Name, ActionID: actionID})
+	}
+
+	return actions, nil
+}
+
+// ScriptResultType is the result of a script run on a system.
+type ScriptResultType struct {
+	System     string `json:"system"`
+	ReturnCode int    `json:"return_code"`
+	Output     string `json:"output"`
+}
+
+// SumaScheduleScriptRun schedules a script for the systems, which is run as username and groupname. The script
+// must start with an interpreter line (#!) and is aborted after timeout seconds.
+func SumaScheduleScriptRun(sessioncookie, susemgr string, systems []SystemType, script, username, groupname string, timeout int, earliest time.Time, verbose bool) (actions []ActionType, err error) {
+
+	type ScheduleScriptRun struct {
+		ServerIds          []int  `json:"sids"`
+		Username           string `json:"username"`
+		Groupname          string `json:"groupname"`
+		Timeout            int    `json:"timeout"`
+		Script             string `json:"script"`
+		EarliestOccurrence string `json:"earliestOccurrence"`
+	}
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaScheduleScriptRun: Enter function")
+		log.Println("DEBUG SUMAAPI SumaScheduleScriptRun: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaScheduleScriptRun: Leave function")
+	}
+
+	if !strings.HasPrefix(script, "#!") {
+		return nil, fmt.Errorf("the script must start with an interpreter line (#!)")
+	}
+
+	if len(systems) == 0 {
+		return nil, nil
+	}
+
+	payload := ScheduleScriptRun{
+		ServerIds:          systemIDs(systems),
+		Username:           username,
+		Groupname:          groupname,
+		Timeout:            timeout,
+		Script:             script,
+		EarliestOccurrence: sumaTime(earliest),
+	}
+
+	var actionID int
+	err = sumaPost(sessioncookie, susemgr, "/system/scheduleScriptRun", payload, &actionID, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not schedule script: %v", err)
+	}
+
+	// one action is scheduled for all systems
+	for _, system := range systems {
+		actions = append(actions, ActionType{System: system.Name, ActionID: actionID})
+	}
+
+	return actions, nil
+}
+
+var sumaGetScriptResults = func(sessioncookie, susemgr string, actionID int, verbose bool) (results map[int]ScriptResultType, err error) {
+
+	type ResultScript struct {
+		ServerID    int    `json:"serverId"`
+		ReturnCode  int    `json:"returnCode"`
+		Output      string `json:"output"`
+		OutputEnc64 bool   `json:"outputEnc64"`
+	}
+
+	var rsp []ResultScript
+	err = sumaGet(sessioncookie, susemgr, "/system/getScriptResults", url.Values{"actionId": {strconv.Itoa(actionID)}}, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	results = make(map[int]ScriptResultType)
+	for _, r := range rsp {
+		output := r.Output
+		if r.OutputEnc64 {
+			decoded, err := base64.StdEncoding.DecodeString(r.Output)
+			if err != nil {
+				return nil, fmt.Errorf("could not decode script output: %v", err)
+			}
+			output = string(decoded)
+		}
+		results[r.ServerID] = ScriptResultType{ReturnCode: r.ReturnCode, Output: output}
+	}
+
+	return results, nil
+}
+
+// SumaWaitForScriptResults waits until the script action is finished on all systems or the timeout is
+// reached. The results reported so far are returned.
+func SumaWaitForScriptResults(sessioncookie, susemgr string, actionID int, systems []SystemType, timeout time.Duration, verbose bool) (results []ScriptResultType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaWaitForScriptResults: Enter function")
+		log.Println("DEBUG SUMAAPI SumaWaitForScriptResults: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaWaitForScriptResults: Leave function")
+	}
+
+	_, err = SumaWaitForAction(sessioncookie, susemgr, actionID, timeout, verbose)
+
+	found, ferr := sumaGetScriptResults(sessioncookie, susemgr, actionID, verbose)
+	if ferr != nil {
+		return nil, ferr
+	}
+
+	for _, system := range systems {
+		if r, ok := found[system.ID]; ok {
+			r.System = system.Name
+			results = append(results, r)
+		}
+	}
+
+	return results, err
+}
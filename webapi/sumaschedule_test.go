@@ -0,0 +1,289 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// patchGroupSystems patches the systems of a group and their IP addresses.
+func patchGroupSystems(t *testing.T, systems map[string]string) {
+	oldListGroupSystems := sumaListGroupSystems
+	oldGetSystemID := sumaGetSystemID
+	oldGetSystemIP := sumaGetSystemIP
+
+	var members []SystemType
+	ids := make(map[string]int)
+	ips := make(map[int]string)
+	id := 1
+	for name, ip := range systems {
+		members = append(members, SystemType{ID: id, Name: name})
+		ids[name] = id
+		ips[id] = ip
+		id++
+	}
+
+	sumaListGroupSystems = func(sessioncookie, susemgr, group string, verbose bool) ([]SystemType, error) {
+		return members, nil
+	}
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		if id, ok := ids[hostname]; ok {
+			return id, nil
+		}
+		return 99, nil
+	}
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		if ip, ok := ips[id]; ok {
+			return ip, nil
+		}
+		return "192.168.1.99", nil
+	}
+
+	t.Cleanup(func() {
+		sumaListGroupSystems = oldListGroupSystems
+		sumaGetSystemID = oldGetSystemID
+		sumaGetSystemIP = oldGetSystemIP
+	})
+}
+
+func TestSumaGetTargetSystems_Group(t *testing.T) {
+	patchGroupSystems(t, map[string]string{
+		"in.example.com":  "192.168.1.10",
+		"out.example.com": "10.0.0.1",
+	})
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	systems, err := SumaGetTargetSystems("cookie", "http://dummy", nil, "group", "192.168.1.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(systems) != 1 || systems[0].Name != "in.example.com" || systems[0].IP != "192.168.1.10" {
+		t.Errorf("expected only in.example.com, got %+v", systems)
+	}
+}
+
+func TestSumaGetTargetSystems_Hostnames(t *testing.T) {
+	patchGroupSystems(t, map[string]string{
+		"in.example.com":  "192.168.1.10",
+		"out.example.com": "10.0.0.1",
+	})
+
+	systems, err := SumaGetTargetSystems("cookie", "http://dummy", []string{"in.example.com"}, "group", "192.168.1.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(systems) != 1 || systems[0].Name != "in.example.com" {
+		t.Errorf("expected in.example.com, got %+v", systems)
+	}
+
+	_, err = SumaGetTargetSystems("cookie", "http://dummy", []string{"out.example.com"}, "group", "192.168.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
+		t.Errorf("expected network error, got %v", err)
+	}
+
+	_, err = SumaGetTargetSystems("cookie", "http://dummy", []string{"other.example.com"}, "group", "192.168.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the group") {
+		t.Errorf("expected group membership error, got %v", err)
+	}
+}
+
+func TestSumaScheduleApplyErrata(t *testing.T) {
+	var earliest string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/getRelevantErrataByType":
+			if r.URL.Query().Get("advisoryType") != AdvisorySecurity {
+				t.Errorf("unexpected advisoryType: %s", r.URL.Query().Get("advisoryType"))
+			}
+			if r.URL.Query().Get("sid") == "1" {
+				fmt.Fprint(w, `{"success": true, "result": [{"id": 11}, {"id": 12}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/system/scheduleApplyErrata":
+			var payload struct {
+				ServerID           int    `json:"sid"`
+				ErrataIds          []int  `json:"errataIds"`
+				EarliestOccurrence string `json:"earliestOccurrence"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.ServerID != 1 || len(payload.ErrataIds) != 2 {
+				t.Errorf("unexpected payload: %+v", payload)
+			}
+			earliest = payload.EarliestOccurrence
+			fmt.Fprint(w, `{"success": true, "result": [100]}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}}
+	at := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+
+	actions, err := SumaScheduleApplyErrata("cookie", server.URL, systems, AdvisorySecurity, at, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(actions) != 1 || actions[0].ActionID != 100 || actions[0].System != "a.example.com" {
+		t.Errorf("unexpected actions: %+v", actions)
+	}
+	if earliest != "2026-10-18T22:00:00Z" {
+		t.Errorf("unexpected earliest occurrence: %s", earliest)
+	}
+}
+
+func TestSumaScheduleReboot_Stagger(t *testing.T) {
+	var earliest []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rhn/manager/api/system/scheduleReboot" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload struct {
+			ServerID           int    `json:"sid"`
+			EarliestOccurrence string `json:"earliestOccurrence"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		earliest = append(earliest, payload.EarliestOccurrence)
+		fmt.Fprintf(w, `{"success": true, "result": %d}`, 200+payload.ServerID)
+	}))
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}}
+	at := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+
+	actions, err := SumaScheduleReboot("cookie", server.URL, systems, at, 10*time.Minute, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(actions) != 2 || actions[0].ActionID != 201 || actions[1].ActionID != 202 {
+		t.Errorf("unexpected actions: %+v", actions)
+	}
+	if len(earliest) != 2 || earliest[0] != "2026-10-18T22:00:00Z" || earliest[1] != "2026-10-18T22:10:00Z" {
+		t.Errorf("unexpected earliest occurrences: %v", earliest)
+	}
+}
+
+func TestSumaFilterRebootRequired(t *testing.T) {
+	oldListSuggestedReboot := sumaListSuggestedReboot
+	sumaListSuggestedReboot = func(sessioncookie, susemgr string, verbose bool) (map[int]bool, error) {
+		return map[int]bool{2: true}, nil
+	}
+	defer func() { sumaListSuggestedReboot = oldListSuggestedReboot }()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}}
+
+	got, err := SumaFilterRebootRequired("cookie", "http://dummy", systems, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("expected only system 2, got %+v", got)
+	}
+}
+
+func TestSumaScheduleApplyHighstate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rhn/manager/api/system/scheduleApplyHighstate" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload struct {
+			ServerIds []int `json:"sids"`
+			Test      bool  `json:"test"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if len(payload.ServerIds) != 2 || !payload.Test {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+		fmt.Fprint(w, `{"success": true, "result": 300}`)
+	}))
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}}
+
+	actions, err := SumaScheduleApplyHighstate("cookie", server.URL, systems, time.Time{}, true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(actions) != 2 || actions[0].ActionID != 300 || actions[1].ActionID != 300 {
+		t.Errorf("unexpected actions: %+v", actions)
+	}
+}
+
+func TestSumaScheduleScriptRun_NoInterpreter(t *testing.T) {
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}}
+
+	_, err := SumaScheduleScriptRun("cookie", "http://dummy", systems, "uptime", "root", "root", 600, time.Time{}, false)
+	if err == nil || !strings.Contains(err.Error(), "interpreter") {
+		t.Errorf("expected interpreter error, got %v", err)
+	}
+}
+
+func TestSumaScheduleScriptRun_WaitForResults(t *testing.T) {
+	oldPollInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() { pollInterval = oldPollInterval }()
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/scheduleScriptRun":
+			var payload struct {
+				ServerIds []int  `json:"sids"`
+				Username  string `json:"username"`
+				Timeout   int    `json:"timeout"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			if len(payload.ServerIds) != 2 || payload.Username != "root" || payload.Timeout != 600 {
+				t.Errorf("unexpected payload: %+v", payload)
+			}
+			fmt.Fprint(w, `{"success": true, "result": 400}`)
+		case "/rhn/manager/api/schedule/listInProgressSystems":
+			polls++
+			if polls == 1 {
+				fmt.Fprint(w, `{"success": true, "result": [{"server_id": 2, "server_name": "b.example.com"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/schedule/listCompletedSystems", "/rhn/manager/api/schedule/listFailedSystems":
+			fmt.Fprint(w, `{"success": true, "result": []}`)
+		case "/rhn/manager/api/system/getScriptResults":
+			fmt.Fprint(w, `{"success": true, "result": [
+				{"serverId": 1, "returnCode": 0, "output": "up"},
+				{"serverId": 2, "returnCode": 1, "output": "ZG93bg==", "outputEnc64": true}]}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	systems := []SystemType{{ID: 1, Name: "a.example.com"}, {ID: 2, Name: "b.example.com"}}
+
+	actions, err := SumaScheduleScriptRun("cookie", server.URL, systems, "#!/bin/sh\nuptime\n", "root", "root", 600, time.Time{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(actions) != 2 || actions[0].ActionID != 400 {
+		t.Fatalf("unexpected actions: %+v", actions)
+	}
+
+	results, err := SumaWaitForScriptResults("cookie", server.URL, 400, systems, time.Second, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[1].System != "b.example.com" || results[1].ReturnCode != 1 || results[1].Output != "down" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if polls != 2 {
+		t.Errorf("expected to poll the action twice, got %d", polls)
+	}
+}
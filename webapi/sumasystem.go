@@ -0,0 +1,249 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+)
+
+// SystemStatusType describes the state of a system in the SUSE Manager.
+type SystemStatusType struct {
+	ID              int               `json:"id"`
+	Name            string            `json:"name"`
+	IP              string            `json:"ip"`
+	Groups          []string          `json:"groups"`
+	BaseChannel     string            `json:"base_channel"`
+	ChildChannels   []string          `json:"child_channels"`
+	LastCheckin     string            `json:"last_checkin"`
+	PendingActions  int               `json:"pending_actions"`
+	RelevantPatches int               `json:"relevant_patches"`
+	RebootRequired  bool              `json:"reboot_required"`
+	CustomValues    map[string]string `json:"custom_values"`
+}
+
+func sidQuery(id int) url.Values {
+	return url.Values{"sid": {strconv.Itoa(id)}}
+}
+
+// sumaAuthorizeSystem get the ID and IP of a system and check, that the system belongs to the permitted network.
+var sumaAuthorizeSystem = func(sessioncookie, susemgr, hostname, network string, verbose bool) (id int, ip string, err error) {
+
+	id, err = sumaGetSystemID(sessioncookie, susemgr, hostname, verbose)
+	if err != nil {
+		return -1, "", err
+	}
+
+	ip, err = sumaGetSystemIP(sessioncookie, susemgr, id, verbose)
+	if err != nil {
+		log.Printf("could not get ip, errorcode: %v\n", err)
+		return -1, "", err
+	}
+
+	if !isSystemInNetwork(ip, network) {
+		return -1, "", fmt.Errorf("%s does not belong to the permitted network of the group", hostname)
+	}
+
+	return id, ip, nil
+}
+
+var sumaListSubscribedGroups = func(sessioncookie, susemgr string, id int, verbose bool) (groups []string, err error) {
+
+	type ResultListGroups struct {
+		Subscribed      int    `json:"subscribed"`
+		SystemGroupName string `json:"system_group_name"`
+	}
+
+	var rsp []ResultListGroups
+	err = sumaGet(sessioncookie, susemgr, "/system/listGroups", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range rsp {
+		if g.Subscribed == 1 {
+			groups = append(groups, g.SystemGroupName)
+		}
+	}
+
+	return groups, nil
+}
+
+var sumaGetBaseChannel = func(sessioncookie, susemgr string, id int, verbose bool) (label string, err error) {
+
+	type ResultChannel struct {
+		Label string `json:"label"`
+	}
+
+	var rsp ResultChannel
+	err = sumaGet(sessioncookie, susemgr, "/system/getSubscribedBaseChannel", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	return rsp.Label, nil
+}
+
+var sumaListChildChannels = func(sessioncookie, susemgr string, id int, verbose bool) (labels []string, err error) {
+
+	type ResultChannel struct {
+		Label string `json:"label"`
+	}
+
+	var rsp []ResultChannel
+	err = sumaGet(sessioncookie, susemgr, "/system/listSubscribedChildChannels", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range rsp {
+		labels = append(labels, c.Label)
+	}
+
+	return labels, nil
+}
+
+var sumaGetLastCheckin = func(sessioncookie, susemgr string, id int, verbose bool) (lastcheckin string, err error) {
+
+	type ResultGetName struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		LastCheckin string `json:"last_checkin"`
+	}
+
+	var rsp ResultGetName
+	err = sumaGet(sessioncookie, susemgr, "/system/getName", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	return rsp.LastCheckin, nil
+}
+
+var sumaCountPendingActions = func(sessioncookie, susemgr string, id int, verbose bool) (count int, err error) {
+
+	type ResultAction struct {
+		ID int `json:"id"`
+	}
+
+	type ResultSystem struct {
+		ServerID int `json:"server_id"`
+	}
+
+	var actions []ResultAction
+	err = sumaGet(sessioncookie, susemgr, "/schedule/listInProgressActions", nil, &actions, verbose)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, a := range actions {
+		var systems []ResultSystem
+		err = sumaGet(sessioncookie, susemgr, "/schedule/listInProgressSystems", url.Values{"actionId": {strconv.Itoa(a.ID)}}, &systems, verbose)
+		if err != nil {
+			return -1, err
+		}
+		for _, s := range systems {
+			if s.ServerID == id {
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
+
+var sumaCountRelevantErrata = func(sessioncookie, susemgr string, id int, verbose bool) (count int, err error) {
+
+	type ResultErrata struct {
+		ID int `json:"id"`
+	}
+
+	var rsp []ResultErrata
+	err = sumaGet(sessioncookie, susemgr, "/system/getRelevantErrata", sidQuery(id), &rsp, verbose)
+	if err != nil {
+		return -1, err
+	}
+
+	return len(rsp), nil
+}
+
+var sumaListSuggestedReboot = func(sessioncookie, susemgr string, verbose bool) (ids map[int]bool, err error) {
+
+	type ResultSystem struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var rsp []ResultSystem
+	err = sumaGet(sessioncookie, susemgr, "/system/listSuggestedReboot", nil, &rsp, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	ids = make(map[int]bool)
+	for _, s := range rsp {
+		ids[s.ID] = true
+	}
+
+	return ids, nil
+}
+
+// SumaGetSystemStatus collects the status of a system. The status is only reported, if the system
+// belongs to the permitted network.
+func SumaGetSystemStatus(sessioncookie, susemgr, hostname, network string, verbose bool) (status SystemStatusType, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaGetSystemStatus: Enter function")
+		log.Println("DEBUG SUMAAPI SumaGetSystemStatus: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaGetSystemStatus: Leave function")
+	}
+
+	status.Name = hostname
+	status.ID, status.IP, err = sumaAuthorizeSystem(sessioncookie, susemgr, hostname, network, verbose)
+	if err != nil {
+		return status, err
+	}
+
+	status.Groups, err = sumaListSubscribedGroups(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get groups of %s: %v", hostname, err)
+	}
+
+	status.BaseChannel, err = sumaGetBaseChannel(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get base channel of %s: %v", hostname, err)
+	}
+
+	status.ChildChannels, err = sumaListChildChannels(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get child channels of %s: %v", hostname, err)
+	}
+
+	status.LastCheckin, err = sumaGetLastCheckin(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get last checkin of %s: %v", hostname, err)
+	}
+
+	status.PendingActions, err = sumaCountPendingActions(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get pending actions of %s: %v", hostname, err)
+	}
+
+	status.RelevantPatches, err = sumaCountRelevantErrata(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get relevant patches of %s: %v", hostname, err)
+	}
+
+	reboot, err := sumaListSuggestedReboot(sessioncookie, susemgr, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get systems requiring a reboot: %v", err)
+	}
+	status.RebootRequired = reboot[status.ID]
+
+	status.CustomValues, err = sumaGetCustomValues(sessioncookie, susemgr, status.ID, verbose)
+	if err != nil {
+		return status, fmt.Errorf("could not get custom values of %s: %v", hostname, err)
+	}
+
+	return status, nil
+}
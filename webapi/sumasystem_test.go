@@ -0,0 +1,99 @@
+package webapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// patchSystemLookup patches sumaGetSystemID and sumaGetSystemIP to return the given values.
+func patchSystemLookup(t *testing.T, id int, ip string) {
+	oldGetSystemID := sumaGetSystemID
+	oldGetSystemIP := sumaGetSystemIP
+	sumaGetSystemID = func(sessioncookie, susemgr, hostname string, verbose bool) (int, error) {
+		return id, nil
+	}
+	sumaGetSystemIP = func(sessioncookie, susemgr string, id int, verbose bool) (string, error) {
+		return ip, nil
+	}
+	t.Cleanup(func() {
+		sumaGetSystemID = oldGetSystemID
+		sumaGetSystemIP = oldGetSystemIP
+	})
+}
+
+func TestSumaGetSystemStatus_Success(t *testing.T) {
+	patchSystemLookup(t, 42, "192.168.1.10")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/system/listGroups":
+			fmt.Fprint(w, `{"success": true, "result": [
+				{"subscribed": 1, "system_group_name": "testgroup"},
+				{"subscribed": 0, "system_group_name": "othergroup"}]}`)
+		case "/rhn/manager/api/system/getSubscribedBaseChannel":
+			fmt.Fprint(w, `{"success": true, "result": {"label": "sles15-sp6-pool"}}`)
+		case "/rhn/manager/api/system/listSubscribedChildChannels":
+			fmt.Fprint(w, `{"success": true, "result": [{"label": "sles15-sp6-updates"}]}`)
+		case "/rhn/manager/api/system/getName":
+			fmt.Fprint(w, `{"success": true, "result": {"id": 42, "name": "host", "last_checkin": "2026-10-01T10:00:00Z"}}`)
+		case "/rhn/manager/api/schedule/listInProgressActions":
+			fmt.Fprint(w, `{"success": true, "result": [{"id": 1}, {"id": 2}]}`)
+		case "/rhn/manager/api/schedule/listInProgressSystems":
+			if r.URL.Query().Get("actionId") == "1" {
+				fmt.Fprint(w, `{"success": true, "result": [{"server_id": 42}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "result": [{"server_id": 43}]}`)
+		case "/rhn/manager/api/system/getRelevantErrata":
+			fmt.Fprint(w, `{"success": true, "result": [{"id": 1}, {"id": 2}, {"id": 3}]}`)
+		case "/rhn/manager/api/system/listSuggestedReboot":
+			fmt.Fprint(w, `{"success": true, "result": [{"id": 42, "name": "host"}]}`)
+		case "/rhn/manager/api/system/getCustomValues":
+			fmt.Fprint(w, `{"success": true, "result": {"owner": "alice"}}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	status, err := SumaGetSystemStatus("cookie", server.URL, "host", "192.168.1.0", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.ID != 42 || status.IP != "192.168.1.10" {
+		t.Errorf("unexpected ID/IP: %d/%s", status.ID, status.IP)
+	}
+	if len(status.Groups) != 1 || status.Groups[0] != "testgroup" {
+		t.Errorf("unexpected groups: %v", status.Groups)
+	}
+	if status.BaseChannel != "sles15-sp6-pool" || len(status.ChildChannels) != 1 {
+		t.Errorf("unexpected channels: %s %v", status.BaseChannel, status.ChildChannels)
+	}
+	if status.LastCheckin != "2026-10-01T10:00:00Z" {
+		t.Errorf("unexpected last checkin: %s", status.LastCheckin)
+	}
+	if status.PendingActions != 1 {
+		t.Errorf("expected 1 pending action, got %d", status.PendingActions)
+	}
+	if status.RelevantPatches != 3 {
+		t.Errorf("expected 3 relevant patches, got %d", status.RelevantPatches)
+	}
+	if !status.RebootRequired {
+		t.Error("expected reboot required")
+	}
+	if status.CustomValues["owner"] != "alice" {
+		t.Errorf("unexpected custom values: %v", status.CustomValues)
+	}
+}
+
+func TestSumaGetSystemStatus_InvalidNetwork(t *testing.T) {
+	patchSystemLookup(t, 42, "10.0.0.1")
+
+	_, err := SumaGetSystemStatus("cookie", "http://dummy", "host", "192.168.1.0", false)
+	if err == nil || !strings.Contains(err.Error(), "does not belong to the permitted network") {
+		t.Errorf("expected network error, got %v", err)
+	}
+}
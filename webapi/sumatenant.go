@@ -0,0 +1,133 @@
+package webapi
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+var sumaListUsers = func(sessioncookie, susemgr string, verbose bool) (users map[string]bool, err error) {
+
+	type ResultUser struct {
+		Login   string `json:"login"`
+		Enabled bool   `json:"enabled"`
+	}
+
+	var rsp []ResultUser
+	err = sumaGet(sessioncookie, susemgr, "/user/listUsers", nil, &rsp, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not list users: %v", err)
+	}
+
+	users = make(map[string]bool)
+	for _, u := range rsp {
+		users[u.Login] = u.Enabled
+	}
+
+	return users, nil
+}
+
+var sumaListSystemGroups = func(sessioncookie, susemgr string, verbose bool) (groups map[string]int, err error) {
+
+	type ResultSystemGroup struct {
+		Name        string `json:"name"`
+		SystemCount int    `json:"system_count"`
+	}
+
+	var rsp []ResultSystemGroup
+	err = sumaGet(sessioncookie, susemgr, "/systemgroup/listAllGroups", nil, &rsp, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("could not list systemgroups: %v", err)
+	}
+
+	groups = make(map[string]int)
+	for _, g := range rsp {
+		groups[g.Name] = g.SystemCount
+	}
+
+	return groups, nil
+}
+
+// SumaListUsers returns the logins of all users of the SUSE Manager.
+func SumaListUsers(sessioncookie, susemgr string, verbose bool) (logins []string, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaListUsers: Enter function")
+		log.Println("DEBUG SUMAAPI SumaListUsers: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaListUsers: Leave function")
+	}
+
+	users, err := sumaListUsers(sessioncookie, susemgr, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for login := range users {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+
+	return logins, nil
+}
+
+// SumaListUserStates returns, if the users of the SUSE Manager are enabled, by login.
+func SumaListUserStates(sessioncookie, susemgr string, verbose bool) (enabled map[string]bool, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaListUserStates: Enter function")
+		log.Println("DEBUG SUMAAPI SumaListUserStates: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaListUserStates: Leave function")
+	}
+
+	return sumaListUsers(sessioncookie, susemgr, verbose)
+}
+
+// SumaListSystemGroups returns the names of all SystemGroups of the SUSE Manager.
+func SumaListSystemGroups(sessioncookie, susemgr string, verbose bool) (groups []string, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaListSystemGroups: Enter function")
+		log.Println("DEBUG SUMAAPI SumaListSystemGroups: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaListSystemGroups: Leave function")
+	}
+
+	counts, err := sumaListSystemGroups(sessioncookie, susemgr, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range counts {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
+	return groups, nil
+}
+
+// SumaListSystemGroupCounts returns the number of systems of all SystemGroups of the SUSE Manager by name.
+func SumaListSystemGroupCounts(sessioncookie, susemgr string, verbose bool) (counts map[string]int, err error) {
+
+	if verbose {
+		log.Println("DEBUG SUMAAPI SumaListSystemGroupCounts: Enter function")
+		log.Println("DEBUG SUMAAPI SumaListSystemGroupCounts: ==============")
+		defer log.Println("DEBUG SUMAAPI SumaListSystemGroupCounts: Leave function")
+	}
+
+	return sumaListSystemGroups(sessioncookie, susemgr, verbose)
+}
+
+// SumaSystemGroupExists reports, if the SystemGroup of the group exists.
+func SumaSystemGroupExists(sessioncookie, susemgrurl, group string, verbose bool) bool {
+	return sumaCheckSystemGroup(sessioncookie, group, susemgrurl, verbose)
+}
+
+// SumaCreateSystemGroup creates the SystemGroup of the group.
+func SumaCreateSystemGroup(sessioncookie, susemgrurl, group string, verbose bool) error {
+	return sumaCreateSystemGroup(sessioncookie, susemgrurl, group, verbose)
+}
+
+// SumaRemoveSystemGroup deletes the SystemGroup of the group. The systems of the group are not deleted.
+func SumaRemoveSystemGroup(sessioncookie, susemgrurl, group string, verbose bool) error {
+	_, err := sumaRemoveSystemGroup(sessioncookie, susemgrurl, group, verbose)
+	return err
+}
@@ -0,0 +1,63 @@
+package webapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSumaListUsersAndSystemGroups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rhn/manager/api/user/listUsers":
+			fmt.Fprint(w, `{"success": true, "result": [{"login": "beta", "enabled": false}, {"login": "admin", "enabled": true}, {"login": "alpha", "enabled": true}]}`)
+		case "/rhn/manager/api/systemgroup/listAllGroups":
+			fmt.Fprint(w, `{"success": true, "result": [{"id": 2, "name": "beta", "system_count": 3}, {"id": 1, "name": "alpha", "system_count": 0}]}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	users, err := SumaListUsers("cookie", server.URL, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(users, ",") != "admin,alpha,beta" {
+		t.Errorf("unexpected users: %v", users)
+	}
+
+	groups, err := SumaListSystemGroups("cookie", server.URL, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(groups, ",") != "alpha,beta" {
+		t.Errorf("unexpected systemgroups: %v", groups)
+	}
+
+	states, err := SumaListUserStates("cookie", server.URL, false)
+	if err != nil || len(states) != 3 || !states["alpha"] || states["beta"] {
+		t.Errorf("unexpected user states: %v %v", states, err)
+	}
+
+	counts, err := SumaListSystemGroupCounts("cookie", server.URL, false)
+	if err != nil || len(counts) != 2 || counts["alpha"] != 0 || counts["beta"] != 3 {
+		t.Errorf("unexpected system counts: %v %v", counts, err)
+	}
+}
+
+func TestSumaListUsers_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	restore := suppressLogOutput(t)
+	defer restore()
+
+	if _, err := SumaListUsers("cookie", server.URL, false); err == nil {
+		t.Error("expected error")
+	}
+}